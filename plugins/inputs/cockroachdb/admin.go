@@ -0,0 +1,88 @@
+package cockroachdb
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// adminHosts returns the base URL of the admin UI of every configured seed
+// and server, in order. Cluster-wide endpoints are requested from the first
+// one that answers.
+func (c *Cockroachdb) adminHosts() []string {
+	var hosts []string
+	seen := make(map[string]bool)
+	for _, s := range append(append([]string{}, c.Seeds...), c.Servers...) {
+		u, err := url.Parse(s)
+		if err != nil || u.Host == "" {
+			continue
+		}
+		base := (&url.URL{Scheme: u.Scheme, Host: u.Host}).String()
+		if !seen[base] {
+			seen[base] = true
+			hosts = append(hosts, base)
+		}
+	}
+	return hosts
+}
+
+// getAdminJSON decodes the response of a cluster-wide endpoint into v,
+// trying each admin host in turn. It returns the host:port that answered.
+func (c *Cockroachdb) getAdminJSON(path string, v interface{}) (string, error) {
+	return c.adminRequest(path, func(address string) error {
+		return c.getJSON(address, v)
+	})
+}
+
+// postAdminJSON posts req as JSON to a cluster-wide endpoint and decodes the
+// response into v, trying each admin host in turn. It returns the host:port
+// that answered.
+func (c *Cockroachdb) postAdminJSON(path string, req, v interface{}) (string, error) {
+	return c.adminRequest(path, func(address string) error {
+		return c.postJSON(address, req, v)
+	})
+}
+
+// adminRequest calls do with the URL of path on each admin host until one
+// succeeds.
+func (c *Cockroachdb) adminRequest(path string, do func(address string) error) (string, error) {
+	var errs []string
+	for _, base := range c.adminHosts() {
+		if err := do(base + path); err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		u, _ := url.Parse(base)
+		return u.Host, nil
+	}
+	return "", fmt.Errorf("no admin host answered %s: %s", path, strings.Join(errs, "; "))
+}
+
+// jsonInt64 decodes 64-bit integers that CockroachDB encodes either as JSON
+// numbers or, following the protobuf JSON mapping, as strings.
+type jsonInt64 int64
+
+func (i *jsonInt64) UnmarshalJSON(b []byte) error {
+	b = bytes.Trim(b, `"`)
+	if len(b) == 0 || string(b) == "null" {
+		*i = 0
+		return nil
+	}
+	n, err := strconv.ParseInt(string(b), 10, 64)
+	if err != nil {
+		return err
+	}
+	*i = jsonInt64(n)
+	return nil
+}
+
+func formatInt64(i jsonInt64) string {
+	return strconv.FormatInt(int64(i), 10)
+}
+
+// timestamp is an HLC timestamp; only the wall time in nanoseconds is used.
+type timestamp struct {
+	WallTime jsonInt64 `json:"wallTime"`
+}
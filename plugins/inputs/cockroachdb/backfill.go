@@ -0,0 +1,257 @@
+package cockroachdb
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+// tsQueryPath queries the time series CockroachDB records of its own
+// metrics, kept at 10s resolution for several days.
+const tsQueryPath = "/ts/query"
+
+// backfillBatchSize is the largest number of series requested from
+// /ts/query at once, so that a request fits in response_timeout.
+const backfillBatchSize = 50
+
+type tsQueryRequest struct {
+	StartNanos  string    `json:"startNanos"`
+	EndNanos    string    `json:"endNanos"`
+	SampleNanos string    `json:"sampleNanos"`
+	Queries     []tsQuery `json:"queries"`
+}
+
+type tsQuery struct {
+	Name        string   `json:"name"`
+	Sources     []string `json:"sources"`
+	Downsampler string   `json:"downsampler"`
+}
+
+type tsQueryResponse struct {
+	Results []struct {
+		Query struct {
+			Name string `json:"name"`
+		} `json:"query"`
+		Datapoints []struct {
+			TimestampNanos jsonInt64 `json:"timestampNanos"`
+			Value          float64   `json:"value"`
+		} `json:"datapoints"`
+	} `json:"results"`
+}
+
+// backfillSource is a node or store reached during a gather, with the
+// metrics it reported. The time series of a node are named cr.node.<metric>
+// and those of a store cr.store.<metric>, with the node or store ID as
+// source.
+type backfillSource struct {
+	measurement string
+	prefix      string
+	source      string
+	tags        map[string]string
+	fields      map[string]interface{}
+}
+
+// backfillSources collects the nodes and stores reached during a gather.
+type backfillSources struct {
+	sync.Mutex
+	sources []*backfillSource
+}
+
+func (b *backfillSources) add(measurement, prefix, source string, tags map[string]string, fields map[string]interface{}) {
+	src := &backfillSource{
+		measurement: measurement,
+		prefix:      prefix,
+		source:      source,
+		tags:        make(map[string]string, len(tags)),
+		fields:      make(map[string]interface{}, len(fields)),
+	}
+	for k, v := range tags {
+		src.tags[k] = v
+	}
+	for k, v := range fields {
+		src.fields[k] = v
+	}
+
+	b.Lock()
+	b.sources = append(b.sources, src)
+	b.Unlock()
+}
+
+// checkBackfill validates backfill_resolution, which /ts/query only accepts
+// as a multiple of the 10s resolution of the time series.
+func (c *Cockroachdb) checkBackfill() error {
+	if !c.Backfill {
+		return nil
+	}
+	d := c.BackfillResolution.Duration
+	if d < 0 || d%(10*time.Second) != 0 {
+		return fmt.Errorf("backfill_resolution %s is not a multiple of 10s", d)
+	}
+	return nil
+}
+
+// gatherBackfill records the time of a successful gather. When the previous
+// one is older than BackfillGap, the node and store metrics of the missed
+// window are requested from /ts/query and added with their original
+// timestamps. The time only moves forward once the window is backfilled, so
+// that a failed backfill is retried on the next gather.
+func (c *Cockroachdb) gatherBackfill(start time.Time, sources *backfillSources, acc telegraf.Accumulator) error {
+	if c.lastGather.IsZero() && c.BackfillStateFile != "" {
+		last, err := readBackfillState(c.BackfillStateFile)
+		if err != nil {
+			acc.AddError(err)
+		}
+		c.lastGather = last
+	}
+
+	// Gathers that reached no node leave a gap to fill later
+	if len(sources.sources) == 0 {
+		return nil
+	}
+
+	last := c.lastGather
+	if !last.IsZero() && start.Sub(last) >= c.BackfillGap.Duration {
+		from := last
+		if c.BackfillMaxAge.Duration > 0 && start.Sub(from) > c.BackfillMaxAge.Duration {
+			from = start.Add(-c.BackfillMaxAge.Duration)
+		}
+		if err := c.backfill(sources.sources, from, start, acc); err != nil {
+			return err
+		}
+	}
+
+	c.lastGather = start
+	if c.BackfillStateFile != "" {
+		return writeBackfillState(c.BackfillStateFile, start)
+	}
+	return nil
+}
+
+// backfill adds the points of the nodes and stores between from and to. The
+// series of each source are requested in batches of backfillBatchSize, and
+// nothing is added unless every batch succeeds.
+func (c *Cockroachdb) backfill(sources []*backfillSource, from, to time.Time, acc telegraf.Accumulator) error {
+	resolution := c.BackfillResolution.Duration
+	if resolution <= 0 {
+		resolution = 10 * time.Second
+	}
+
+	// Regroup the series into one point per source and timestamp
+	points := make(map[*backfillSource]map[int64]map[string]interface{})
+	for _, src := range sources {
+		names := make([]string, 0, len(src.fields))
+		for name := range src.fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for len(names) > 0 {
+			n := len(names)
+			if n > backfillBatchSize {
+				n = backfillBatchSize
+			}
+			req := tsQueryRequest{
+				StartNanos:  strconv.FormatInt(from.UnixNano(), 10),
+				EndNanos:    strconv.FormatInt(to.UnixNano(), 10),
+				SampleNanos: strconv.FormatInt(int64(resolution), 10),
+			}
+			for _, name := range names[:n] {
+				req.Queries = append(req.Queries, tsQuery{
+					Name:        src.prefix + name,
+					Sources:     []string{src.source},
+					Downsampler: "AVG",
+				})
+			}
+
+			var resp tsQueryResponse
+			if _, err := c.postAdminJSON(tsQueryPath, req, &resp); err != nil {
+				return err
+			}
+			// The results are returned in the order of the queries
+			if len(resp.Results) != len(req.Queries) {
+				return fmt.Errorf("%s answered %d results for %d queries", tsQueryPath, len(resp.Results), len(req.Queries))
+			}
+
+			if points[src] == nil {
+				points[src] = make(map[int64]map[string]interface{})
+			}
+			for i, result := range resp.Results {
+				name := names[i]
+				for _, dp := range result.Datapoints {
+					ts := int64(dp.TimestampNanos)
+					if points[src][ts] == nil {
+						points[src][ts] = make(map[string]interface{})
+					}
+					points[src][ts][name] = sameType(src.fields[name], dp.Value)
+				}
+			}
+			names = names[n:]
+		}
+	}
+
+	for _, src := range sources {
+		var timestamps []int64
+		for ts := range points[src] {
+			timestamps = append(timestamps, ts)
+		}
+		sort.Slice(timestamps, func(i, j int) bool { return timestamps[i] < timestamps[j] })
+
+		for _, ts := range timestamps {
+			tags := make(map[string]string, len(src.tags))
+			for k, v := range src.tags {
+				tags[k] = v
+			}
+			fields := points[src][ts]
+			t := time.Unix(0, ts)
+			c.addSummaries(fields, tags, acc, t)
+			c.addMetrics(src.measurement, fields, tags, acc, t)
+		}
+	}
+	return nil
+}
+
+// sameType converts a time series value to the type of the gathered field,
+// so that backfilled and gathered points do not conflict. Node and store
+// metrics are float64 like the time series; only the store capacity fields
+// taken from the store descriptor are integers.
+func sameType(field interface{}, v float64) interface{} {
+	switch field.(type) {
+	case int64:
+		return int64(math.Round(v))
+	case uint64:
+		return uint64(math.Round(v))
+	case int:
+		return int(math.Round(v))
+	}
+	return v
+}
+
+// readBackfillState returns the time of the last successful gather saved in
+// path, or the zero time when it does not exist yet.
+func readBackfillState(path string) (time.Time, error) {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(string(b)))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid backfill state in %s: %s", path, err)
+	}
+	return t, nil
+}
+
+func writeBackfillState(path string, t time.Time) error {
+	return ioutil.WriteFile(path, []byte(t.Format(time.RFC3339Nano)+"\n"), 0644)
+}
@@ -0,0 +1,90 @@
+package cockroachdb
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+// certificatesPath lists the certificates loaded by a node, followed by the
+// node ID.
+const certificatesPath = "/_status/certificates/"
+
+type certificatesResponse struct {
+	Certificates []struct {
+		Type         string `json:"type"`
+		ErrorMessage string `json:"errorMessage"`
+		Fields       []struct {
+			ValidFrom  jsonInt64 `json:"validFrom"`
+			ValidUntil jsonInt64 `json:"validUntil"`
+		} `json:"fields"`
+	} `json:"certificates"`
+}
+
+// gatherCertificates adds a cockroachdb_certificate point per node and
+// certificate type with the validity of the certificate. When a file holds
+// several certificates, the one expiring first is reported. Certificates
+// that the node failed to load are reported with an error status instead.
+func (c *Cockroachdb) gatherCertificates(acc telegraf.Accumulator) error {
+	var nodes struct {
+		Nodes []struct {
+			Desc struct {
+				NodeID int `json:"nodeId"`
+			} `json:"desc"`
+		} `json:"nodes"`
+	}
+	if _, err := c.getAdminJSON(nodesPath, &nodes); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, node := range nodes.Nodes {
+		nodeID := strconv.Itoa(node.Desc.NodeID)
+
+		var certs certificatesResponse
+		server, err := c.getAdminJSON(certificatesPath+nodeID, &certs)
+		if err != nil {
+			acc.AddError(err)
+			continue
+		}
+
+		for _, cert := range certs.Certificates {
+			tags := map[string]string{
+				"node_id": nodeID,
+				"type":    strings.ToLower(cert.Type),
+				"server":  server,
+			}
+
+			if cert.ErrorMessage != "" || len(cert.Fields) == 0 {
+				acc.AddFields("cockroachdb_certificate", map[string]interface{}{
+					"status": "error",
+					"error":  cert.ErrorMessage,
+				}, tags)
+				continue
+			}
+
+			first := cert.Fields[0]
+			for _, f := range cert.Fields[1:] {
+				if f.ValidUntil < first.ValidUntil {
+					first = f
+				}
+			}
+			notBefore := time.Unix(0, int64(first.ValidFrom))
+			notAfter := time.Unix(0, int64(first.ValidUntil))
+
+			status := "ok"
+			if now.After(notAfter) {
+				status = "expired"
+			}
+			acc.AddFields("cockroachdb_certificate", map[string]interface{}{
+				"status":             status,
+				"not_before":         notBefore.Unix(),
+				"not_after":          notAfter.Unix(),
+				"expires_in_seconds": notAfter.Sub(now).Seconds(),
+			}, tags)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+package cockroachdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"time"
+)
+
+// loginPath creates an admin UI session on secure clusters.
+const loginPath = "/_admin/v1/login"
+
+// createHTTPClient builds the HTTP client from the TLS options. The cookie
+// jar keeps the session cookie handed out by the admin UI login.
+func (c *Cockroachdb) createHTTPClient() (*http.Client, error) {
+	tlsCfg, err := c.ClientConfig.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.ResponseTimeout.Duration < time.Second {
+		c.ResponseTimeout.Duration = time.Second * 5
+	}
+
+	tr := &http.Transport{
+		ResponseHeaderTimeout: c.ResponseTimeout.Duration,
+		TLSClientConfig:       tlsCfg,
+	}
+	client := &http.Client{
+		Transport: tr,
+		Jar:       jar,
+		Timeout:   c.ResponseTimeout.Duration,
+	}
+	return client, nil
+}
+
+// get performs a GET request against address. When credentials are
+// configured and the cluster answers 401, it logs in to the admin UI and
+// retries the request once with the new session.
+func (c *Cockroachdb) get(address string) (*http.Response, error) {
+	resp, err := c.getWithRetries(address)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || c.Username == "" {
+		return resp, err
+	}
+	resp.Body.Close()
+
+	if err := c.login(address); err != nil {
+		return nil, err
+	}
+	return c.getWithRetries(address)
+}
+
+// post performs a POST request of a JSON body against address, logging in
+// first when the cluster answers 401 like get.
+func (c *Cockroachdb) post(address string, body []byte) (*http.Response, error) {
+	resp, err := c.client.Post(address, "application/json", bytes.NewReader(body))
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || c.Username == "" {
+		return resp, err
+	}
+	resp.Body.Close()
+
+	if err := c.login(address); err != nil {
+		return nil, err
+	}
+	return c.client.Post(address, "application/json", bytes.NewReader(body))
+}
+
+// postJSON posts req as JSON to address and decodes the JSON response body
+// into v.
+func (c *Cockroachdb) postJSON(address string, req, v interface{}) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.post(address, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return &statusError{
+			msg:  fmt.Sprintf("Cockroachdb responded with unexepcted status code %d from %s", resp.StatusCode, address),
+			code: resp.StatusCode,
+		}
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return &decodeError{msg: fmt.Sprintf("unable to decode Cockroachdb response: %s", err)}
+	}
+	return nil
+}
+
+// getWithRetries repeats a GET request up to Retries times when it fails
+// with a transport error or a 5xx status code, doubling the wait between
+// attempts starting at RetryBackoff.
+func (c *Cockroachdb) getWithRetries(address string) (*http.Response, error) {
+	backoff := c.RetryBackoff.Duration
+	for attempt := 0; ; attempt++ {
+		resp, err := c.client.Get(address)
+		if attempt >= c.Retries {
+			return resp, err
+		}
+		if err == nil {
+			if resp.StatusCode < http.StatusInternalServerError {
+				return resp, nil
+			}
+			resp.Body.Close()
+		}
+
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// statusError is returned when a request is answered with an unexpected
+// status code.
+type statusError struct {
+	msg  string
+	code int
+}
+
+func (e *statusError) Error() string {
+	return e.msg
+}
+
+// decodeError is returned when a response body cannot be decoded.
+type decodeError struct {
+	msg string
+}
+
+func (e *decodeError) Error() string {
+	return e.msg
+}
+
+// login posts the configured credentials to the admin UI of the host behind
+// address. The session cookie of the response is stored in the client's
+// cookie jar.
+func (c *Cockroachdb) login(address string) error {
+	u, err := url.Parse(address)
+	if err != nil {
+		return err
+	}
+	login := url.URL{Scheme: u.Scheme, Host: u.Host, Path: loginPath}
+
+	body, err := json.Marshal(map[string]string{
+		"username": c.Username,
+		"password": c.Password,
+	})
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.client.Post(login.String(), "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return &statusError{
+			msg:  fmt.Sprintf("Cockroachdb login as %s failed with status code %d", c.Username, resp.StatusCode),
+			code: resp.StatusCode,
+		}
+	}
+	return nil
+}
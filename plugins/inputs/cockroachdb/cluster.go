@@ -0,0 +1,191 @@
+package cockroachdb
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/tls"
+)
+
+// Cluster is an additional cluster monitored by the plugin, configured in a
+// [[inputs.cockroachdb.cluster]] table. It shares every other option of the
+// plugin.
+type Cluster struct {
+	Name    string
+	Servers []string
+	Seeds   []string
+
+	Username string
+	Password string
+	tls.ClientConfig
+}
+
+// newClusterInput returns a copy of the plugin options that gathers the
+// given cluster, with its own client and state.
+func (c *Cockroachdb) newClusterInput(cl *Cluster) *Cockroachdb {
+	cc := *c
+	cc.Cluster = nil
+	cc.ClusterName = cl.Name
+	cc.clusters = nil
+	cc.Servers = cl.Servers
+	cc.Seeds = cl.Seeds
+	cc.Username = cl.Username
+	cc.Password = cl.Password
+	cc.ClientConfig = cl.ClientConfig
+
+	// SQL mode only applies to the servers and seeds of the plugin
+	cc.SQLAddress = ""
+	if cc.BackfillStateFile != "" {
+		cc.BackfillStateFile += "." + cl.Name
+	}
+
+	cc.client = nil
+	cc.metricFilter = nil
+	cc.databaseFilter = nil
+	cc.tableFilter = nil
+	cc.lastTableStats = time.Time{}
+	cc.lastEvent = time.Time{}
+	cc.lastEventIDs = nil
+	cc.rollup = nil
+	cc.nodeHosts = nil
+	cc.clusterID = ""
+	cc.clusterIDFailed = time.Time{}
+	cc.lastGather = time.Time{}
+	cc.backfillSrcs = nil
+	return &cc
+}
+
+// gatherClusters gathers every cluster table concurrently. Their metrics are
+// tagged with cluster_name and cluster_id.
+func (c *Cockroachdb) gatherClusters(acc telegraf.Accumulator) {
+	if c.clusters == nil {
+		for _, cl := range c.Cluster {
+			c.clusters = append(c.clusters, c.newClusterInput(cl))
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i, cl := range c.Cluster {
+		wg.Add(1)
+		go func(cl *Cluster, cc *Cockroachdb) {
+			defer wg.Done()
+			if cl.Name == "" {
+				acc.AddError(fmt.Errorf("cluster table without a name"))
+				return
+			}
+			if len(cc.Servers) == 0 && len(cc.Seeds) == 0 {
+				acc.AddError(fmt.Errorf("cluster %s: no servers or seeds configured", cl.Name))
+				return
+			}
+			acc.AddError(cc.gatherNamedCluster(cl.Name, acc))
+		}(cl, c.clusters[i])
+	}
+	wg.Wait()
+}
+
+// gatherNamedCluster gathers the servers and seeds of a cluster, tagging its
+// metrics with the ID of the cluster and with its name, when set.
+func (c *Cockroachdb) gatherNamedCluster(name string, acc telegraf.Accumulator) error {
+	clusterAcc := &clusterAccumulator{
+		Accumulator: acc,
+		name:        name,
+		tags:        make(map[string]string),
+	}
+	if name != "" {
+		clusterAcc.tags["cluster_name"] = name
+	}
+	if err := c.init(); err != nil {
+		return clusterAcc.wrapError(err)
+	}
+
+	// The ID is resolved once per gather, here, and added to every metric
+	// by clusterAcc. Metrics of a cluster that does not report its ID are
+	// only tagged with its name.
+	id, err := c.getClusterID()
+	if err != nil {
+		clusterAcc.AddError(err)
+	} else if id != "" {
+		clusterAcc.tags["cluster_id"] = id
+	}
+
+	return clusterAcc.wrapError(c.gather(clusterAcc))
+}
+
+// clusterIDRetry is the time to wait after a failed request of the cluster
+// ID before requesting it again.
+const clusterIDRetry = 10 * time.Minute
+
+// getClusterID returns the ID of the cluster, requested once from the first
+// admin host that answers. After a failure the ID is left empty, without
+// further requests nor errors, for clusterIDRetry.
+func (c *Cockroachdb) getClusterID() (string, error) {
+	if c.clusterID != "" {
+		return c.clusterID, nil
+	}
+	if !c.clusterIDFailed.IsZero() && time.Since(c.clusterIDFailed) < clusterIDRetry {
+		return "", nil
+	}
+
+	var cluster struct {
+		ClusterID string `json:"clusterId"`
+	}
+	if _, err := c.getAdminJSON(clusterPath, &cluster); err != nil {
+		c.clusterIDFailed = time.Now()
+		return "", err
+	}
+	c.clusterID = cluster.ClusterID
+	c.clusterIDFailed = time.Time{}
+	return c.clusterID, nil
+}
+
+// clusterAccumulator adds the identity tags of a cluster to every metric,
+// and its name, when set, to every error.
+type clusterAccumulator struct {
+	telegraf.Accumulator
+	name string
+	tags map[string]string
+}
+
+func (a *clusterAccumulator) withTags(tags map[string]string) map[string]string {
+	t := make(map[string]string, len(tags)+len(a.tags))
+	for k, v := range tags {
+		t[k] = v
+	}
+	for k, v := range a.tags {
+		t[k] = v
+	}
+	return t
+}
+
+func (a *clusterAccumulator) AddFields(measurement string, fields map[string]interface{}, tags map[string]string, t ...time.Time) {
+	a.Accumulator.AddFields(measurement, fields, a.withTags(tags), t...)
+}
+
+func (a *clusterAccumulator) AddGauge(measurement string, fields map[string]interface{}, tags map[string]string, t ...time.Time) {
+	a.Accumulator.AddGauge(measurement, fields, a.withTags(tags), t...)
+}
+
+func (a *clusterAccumulator) AddCounter(measurement string, fields map[string]interface{}, tags map[string]string, t ...time.Time) {
+	a.Accumulator.AddCounter(measurement, fields, a.withTags(tags), t...)
+}
+
+func (a *clusterAccumulator) AddSummary(measurement string, fields map[string]interface{}, tags map[string]string, t ...time.Time) {
+	a.Accumulator.AddSummary(measurement, fields, a.withTags(tags), t...)
+}
+
+func (a *clusterAccumulator) AddHistogram(measurement string, fields map[string]interface{}, tags map[string]string, t ...time.Time) {
+	a.Accumulator.AddHistogram(measurement, fields, a.withTags(tags), t...)
+}
+
+func (a *clusterAccumulator) AddError(err error) {
+	a.Accumulator.AddError(a.wrapError(err))
+}
+
+func (a *clusterAccumulator) wrapError(err error) error {
+	if err == nil || a.name == "" {
+		return err
+	}
+	return fmt.Errorf("cluster %s: %s", a.name, err)
+}
@@ -1,31 +1,132 @@
 package cockroachdb
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"math"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/tls"
 	"github.com/influxdata/telegraf/plugins/inputs"
 )
 
+// nodesPath lists the status of every node in the cluster.
+const nodesPath = "/_status/nodes"
+
 type Cockroachdb struct {
-	Servers []string
+	Servers     []string
+	Seeds       []string
+	Source      string
+	ClusterName string `toml:"cluster_name"`
+
+	MetricInclude []string `toml:"metric_include"`
+	MetricExclude []string `toml:"metric_exclude"`
+
+	PercentileSummaries bool `toml:"percentile_summaries"`
+
+	MeasurementNaming string `toml:"measurement_naming"`
+	FieldNaming       string `toml:"field_naming"`
+
+	// Cluster-wide measurements
+	Liveness          bool
+	Ranges            bool
+	ProblemRangeLimit int `toml:"problem_range_limit"`
+	Jobs              bool
+	Peers             bool
+	HotRanges         bool `toml:"hot_ranges"`
+	HotRangesLimit    int  `toml:"hot_ranges_limit"`
+
+	Statements          bool
+	StatementsLimit     int    `toml:"statements_limit"`
+	StatementsSortBy    string `toml:"statements_sort_by"`
+	StatementsQueryText bool   `toml:"statements_query_text"`
+
+	Sessions           bool
+	SessionsLocal      bool              `toml:"sessions_local"`
+	LongQueryThreshold internal.Duration `toml:"long_query_threshold"`
+
+	Events       bool
+	Certificates bool
+
+	Tables             bool
+	TableStatsInterval internal.Duration `toml:"table_stats_interval"`
+	DatabaseInclude    []string          `toml:"database_include"`
+	DatabaseExclude    []string          `toml:"database_exclude"`
+	TableInclude       []string          `toml:"table_include"`
+	TableExclude       []string          `toml:"table_exclude"`
+
+	ClusterRollup bool `toml:"cluster_rollup"`
+	ExpectedNodes int  `toml:"expected_nodes"`
+
+	// Backfill of the gaps between gathers from /ts/query
+	Backfill           bool
+	BackfillResolution internal.Duration `toml:"backfill_resolution"`
+	BackfillGap        internal.Duration `toml:"backfill_gap"`
+	BackfillMaxAge     internal.Duration `toml:"backfill_max_age"`
+	BackfillStateFile  string            `toml:"backfill_state_file"`
+
+	// SQL mode over pgwire
+	SQLAddress        string     `toml:"sql_address"`
+	SQLBuiltinQueries bool       `toml:"sql_builtin_queries"`
+	SQLQuery          []SQLQuery `toml:"sql_query"`
+
+	// Requests to the admin UI. Servers are gathered by up to Workers
+	// concurrent scrapes.
+	ResponseTimeout internal.Duration `toml:"response_timeout"`
+	Workers         int
+	Retries         int
+	RetryBackoff    internal.Duration `toml:"retry_backoff"`
+
+	// Admin UI credentials of a secure cluster
+	Username string
+	Password string
+	tls.ClientConfig
+
+	// Additional clusters, each with its own servers, seeds and credentials
+	Cluster []*Cluster `toml:"cluster"`
 
 	// HTTP client & request
 	client *http.Client
+
+	metricFilter    filter.Filter
+	databaseFilter  filter.Filter
+	tableFilter     filter.Filter
+	lastTableStats  time.Time
+	lastEvent       time.Time
+	lastEventIDs    map[string]bool
+	rollup          *clusterRollup
+	nodeHosts       *nodeHosts
+	clusterID       string
+	clusterIDFailed time.Time
+	clusters        []*Cockroachdb
+	lastGather      time.Time
+	backfillSrcs    *backfillSources
 }
 
-// NewCockroachdb return a new instance of Cockroachdb with a default http client
+// NewCockroachdb return a new instance of Cockroachdb. The http client is
+// created on the first gather, once the TLS options are known.
 func NeCockroachdb() *Cockroachdb {
-	tr := &http.Transport{ResponseHeaderTimeout: time.Duration(3 * time.Second)}
-	client := &http.Client{
-		Transport: tr,
-		Timeout:   time.Duration(4 * time.Second),
+	return &Cockroachdb{
+		SQLBuiltinQueries:  true,
+		ResponseTimeout:    internal.Duration{Duration: 5 * time.Second},
+		Workers:            5,
+		HotRangesLimit:     10,
+		StatementsLimit:    20,
+		StatementsSortBy:   "total_latency",
+		TableStatsInterval: internal.Duration{Duration: 10 * time.Minute},
+		BackfillResolution: internal.Duration{Duration: 10 * time.Second},
+		BackfillGap:        internal.Duration{Duration: time.Minute},
+		BackfillMaxAge:     internal.Duration{Duration: time.Hour},
+		RetryBackoff:       internal.Duration{Duration: 500 * time.Millisecond},
 	}
-	return &Cockroachdb{client: client}
 }
 
 type Cockroach struct {
@@ -35,12 +136,11 @@ type Cockroach struct {
 			NetworkField string `json:"networkField"`
 			AddressField string `json:"addressField"`
 		} `json:"address"`
-		Attrs struct {
-			Attrs []interface{} `json:"attrs"`
-		} `json:"attrs"`
-		Locality struct {
-			Tiers []interface{} `json:"tiers"`
-		} `json:"locality"`
+		HTTPAddress struct {
+			AddressField string `json:"addressField"`
+		} `json:"httpAddress"`
+		Attrs         Attributes `json:"attrs"`
+		Locality      Locality   `json:"locality"`
 		ServerVersion struct {
 			MajorVal int `json:"majorVal"`
 			MinorVal int `json:"minorVal"`
@@ -61,503 +161,125 @@ type Cockroach struct {
 		Channel         string      `json:"channel"`
 		Dependencies    interface{} `json:"dependencies"`
 	} `json:"buildInfo"`
-	StartedAt string `json:"startedAt"`
-	UpdatedAt string `json:"updatedAt"`
-	Metrics   struct {
-		BuildTimestamp                    int     `json:"build.timestamp"`
-		ClockOffsetMeannanos              int     `json:"clock-offset.meannanos"`
-		ClockOffsetStddevnanos            int     `json:"clock-offset.stddevnanos"`
-		DistsenderBatches                 int     `json:"distsender.batches"`
-		DistsenderBatchesPartial          int     `json:"distsender.batches.partial"`
-		DistsenderErrorsNotleaseholder    int     `json:"distsender.errors.notleaseholder"`
-		DistsenderRPCSent                 int     `json:"distsender.rpc.sent"`
-		DistsenderRPCSentLocal            int     `json:"distsender.rpc.sent.local"`
-		DistsenderRPCSentNextreplicaerror int     `json:"distsender.rpc.sent.nextreplicaerror"`
-		ExecError                         int     `json:"exec.error"`
-		ExecLatencyMax                    int     `json:"exec.latency-max"`
-		ExecLatencyP50                    int     `json:"exec.latency-p50"`
-		ExecLatencyP75                    int     `json:"exec.latency-p75"`
-		ExecLatencyP90                    int     `json:"exec.latency-p90"`
-		ExecLatencyP99                    int     `json:"exec.latency-p99"`
-		ExecLatencyP999                   int     `json:"exec.latency-p99.9"`
-		ExecLatencyP9999                  int     `json:"exec.latency-p99.99"`
-		ExecLatencyP99999                 int     `json:"exec.latency-p99.999"`
-		ExecSuccess                       int     `json:"exec.success"`
-		GossipBytesReceived               int     `json:"gossip.bytes.received"`
-		GossipBytesSent                   int     `json:"gossip.bytes.sent"`
-		GossipConnectionsIncoming         int     `json:"gossip.connections.incoming"`
-		GossipConnectionsOutgoing         int     `json:"gossip.connections.outgoing"`
-		GossipConnectionsRefused          int     `json:"gossip.connections.refused"`
-		GossipInfosReceived               int     `json:"gossip.infos.received"`
-		GossipInfosSent                   int     `json:"gossip.infos.sent"`
-		LivenessEpochincrements           int     `json:"liveness.epochincrements"`
-		LivenessHeartbeatfailures         int     `json:"liveness.heartbeatfailures"`
-		LivenessHeartbeatlatencyMax       int     `json:"liveness.heartbeatlatency-max"`
-		LivenessHeartbeatlatencyP50       int     `json:"liveness.heartbeatlatency-p50"`
-		LivenessHeartbeatlatencyP75       int     `json:"liveness.heartbeatlatency-p75"`
-		LivenessHeartbeatlatencyP90       int     `json:"liveness.heartbeatlatency-p90"`
-		LivenessHeartbeatlatencyP99       int     `json:"liveness.heartbeatlatency-p99"`
-		LivenessHeartbeatlatencyP999      int     `json:"liveness.heartbeatlatency-p99.9"`
-		LivenessHeartbeatlatencyP9999     int     `json:"liveness.heartbeatlatency-p99.99"`
-		LivenessHeartbeatlatencyP99999    int     `json:"liveness.heartbeatlatency-p99.999"`
-		LivenessHeartbeatsuccesses        int     `json:"liveness.heartbeatsuccesses"`
-		LivenessLivenodes                 int     `json:"liveness.livenodes"`
-		NodeID                            int     `json:"node-id"`
-		RequestsSlowDistsender            int     `json:"requests.slow.distsender"`
-		RoundTripLatencyMax               int     `json:"round-trip-latency-max"`
-		RoundTripLatencyP50               int     `json:"round-trip-latency-p50"`
-		RoundTripLatencyP75               int     `json:"round-trip-latency-p75"`
-		RoundTripLatencyP90               int     `json:"round-trip-latency-p90"`
-		RoundTripLatencyP99               int     `json:"round-trip-latency-p99"`
-		RoundTripLatencyP999              int     `json:"round-trip-latency-p99.9"`
-		RoundTripLatencyP9999             int     `json:"round-trip-latency-p99.99"`
-		RoundTripLatencyP99999            int     `json:"round-trip-latency-p99.999"`
-		SqlBytesin                        int     `json:"sql.bytesin"`
-		SqlBytesout                       int     `json:"sql.bytesout"`
-		SqlConns                          int     `json:"sql.conns"`
-		SqlDdlCount                       int     `json:"sql.ddl.count"`
-		SqlDeleteCount                    int     `json:"sql.delete.count"`
-		SqlDistsqlExecLatencyMax          int     `json:"sql.distsql.exec.latency-max"`
-		SqlDistsqlExecLatencyP50          int     `json:"sql.distsql.exec.latency-p50"`
-		SqlDistsqlExecLatencyP75          int     `json:"sql.distsql.exec.latency-p75"`
-		SqlDistsqlExecLatencyP90          int     `json:"sql.distsql.exec.latency-p90"`
-		SqlDistsqlExecLatencyP99          int     `json:"sql.distsql.exec.latency-p99"`
-		SqlDistsqlExecLatencyP999         int     `json:"sql.distsql.exec.latency-p99.9"`
-		SqlDistsqlExecLatencyP9999        int     `json:"sql.distsql.exec.latency-p99.99"`
-		SqlDistsqlExecLatencyP99999       int     `json:"sql.distsql.exec.latency-p99.999"`
-		SqlDistsqlFlowsActive             int     `json:"sql.distsql.flows.active"`
-		SqlDistsqlFlowsTotal              int     `json:"sql.distsql.flows.total"`
-		SqlDistsqlQueriesActive           int     `json:"sql.distsql.queries.active"`
-		SqlDistsqlQueriesTotal            int     `json:"sql.distsql.queries.total"`
-		SqlDistsqlSelectCount             int     `json:"sql.distsql.select.count"`
-		SqlDistsqlServiceLatencyMax       int     `json:"sql.distsql.service.latency-max"`
-		SqlDistsqlServiceLatencyP50       int     `json:"sql.distsql.service.latency-p50"`
-		SqlDistsqlServiceLatencyP75       int     `json:"sql.distsql.service.latency-p75"`
-		SqlDistsqlServiceLatencyP90       int     `json:"sql.distsql.service.latency-p90"`
-		SqlDistsqlServiceLatencyP99       int     `json:"sql.distsql.service.latency-p99"`
-		SqlDistsqlServiceLatencyP999      int     `json:"sql.distsql.service.latency-p99.9"`
-		SqlDistsqlServiceLatencyP9999     int     `json:"sql.distsql.service.latency-p99.99"`
-		SqlDistsqlServiceLatencyP99999    int     `json:"sql.distsql.service.latency-p99.999"`
-		SqlExecLatencyMax                 int     `json:"sql.exec.latency-max"`
-		SqlExecLatencyP50                 int     `json:"sql.exec.latency-p50"`
-		SqlExecLatencyP75                 int     `json:"sql.exec.latency-p75"`
-		SqlExecLatencyP90                 int     `json:"sql.exec.latency-p90"`
-		SqlExecLatencyP99                 int     `json:"sql.exec.latency-p99"`
-		SqlExecLatencyP999                int     `json:"sql.exec.latency-p99.9"`
-		SqlExecLatencyP9999               int     `json:"sql.exec.latency-p99.99"`
-		SqlExecLatencyP99999              int     `json:"sql.exec.latency-p99.999"`
-		SqlInsertCount                    int     `json:"sql.insert.count"`
-		SqlMemAdminCurrent                int     `json:"sql.mem.admin.current"`
-		SqlMemAdminMaxMax                 int     `json:"sql.mem.admin.max-max"`
-		SqlMemAdminMaxP50                 int     `json:"sql.mem.admin.max-p50"`
-		SqlMemAdminMaxP75                 int     `json:"sql.mem.admin.max-p75"`
-		SqlMemAdminMaxP90                 int     `json:"sql.mem.admin.max-p90"`
-		SqlMemAdminMaxP99                 int     `json:"sql.mem.admin.max-p99"`
-		SqlMemAdminMaxP999                int     `json:"sql.mem.admin.max-p99.9"`
-		SqlMemAdminMaxP9999               int     `json:"sql.mem.admin.max-p99.99"`
-		SqlMemAdminMaxP99999              int     `json:"sql.mem.admin.max-p99.999"`
-		SqlMemAdminSessionCurrent         int     `json:"sql.mem.admin.session.current"`
-		SqlMemAdminSessionMaxMax          int     `json:"sql.mem.admin.session.max-max"`
-		SqlMemAdminSessionMaxP50          int     `json:"sql.mem.admin.session.max-p50"`
-		SqlMemAdminSessionMaxP75          int     `json:"sql.mem.admin.session.max-p75"`
-		SqlMemAdminSessionMaxP90          int     `json:"sql.mem.admin.session.max-p90"`
-		SqlMemAdminSessionMaxP99          int     `json:"sql.mem.admin.session.max-p99"`
-		SqlMemAdminSessionMaxP999         int     `json:"sql.mem.admin.session.max-p99.9"`
-		SqlMemAdminSessionMaxP9999        int     `json:"sql.mem.admin.session.max-p99.99"`
-		SqlMemAdminSessionMaxP99999       int     `json:"sql.mem.admin.session.max-p99.999"`
-		SqlMemAdminTxnCurrent             int     `json:"sql.mem.admin.txn.current"`
-		SqlMemAdminTxnMaxMax              int     `json:"sql.mem.admin.txn.max-max"`
-		SqlMemAdminTxnMaxP50              int     `json:"sql.mem.admin.txn.max-p50"`
-		SqlMemAdminTxnMaxP75              int     `json:"sql.mem.admin.txn.max-p75"`
-		SqlMemAdminTxnMaxP90              int     `json:"sql.mem.admin.txn.max-p90"`
-		SqlMemAdminTxnMaxP99              int     `json:"sql.mem.admin.txn.max-p99"`
-		SqlMemAdminTxnMaxP999             int     `json:"sql.mem.admin.txn.max-p99.9"`
-		SqlMemAdminTxnMaxP9999            int     `json:"sql.mem.admin.txn.max-p99.99"`
-		SqlMemAdminTxnMaxP99999           int     `json:"sql.mem.admin.txn.max-p99.999"`
-		SqlMemClientCurrent               int     `json:"sql.mem.client.current"`
-		SqlMemClientMaxMax                int     `json:"sql.mem.client.max-max"`
-		SqlMemClientMaxP50                int     `json:"sql.mem.client.max-p50"`
-		SqlMemClientMaxP75                int     `json:"sql.mem.client.max-p75"`
-		SqlMemClientMaxP90                int     `json:"sql.mem.client.max-p90"`
-		SqlMemClientMaxP99                int     `json:"sql.mem.client.max-p99"`
-		SqlMemClientMaxP999               int     `json:"sql.mem.client.max-p99.9"`
-		SqlMemClientMaxP9999              int     `json:"sql.mem.client.max-p99.99"`
-		SqlMemClientMaxP99999             int     `json:"sql.mem.client.max-p99.999"`
-		SqlMemClientSessionCurrent        int     `json:"sql.mem.client.session.current"`
-		SqlMemClientSessionMaxMax         int     `json:"sql.mem.client.session.max-max"`
-		SqlMemClientSessionMaxP50         int     `json:"sql.mem.client.session.max-p50"`
-		SqlMemClientSessionMaxP75         int     `json:"sql.mem.client.session.max-p75"`
-		SqlMemClientSessionMaxP90         int     `json:"sql.mem.client.session.max-p90"`
-		SqlMemClientSessionMaxP99         int     `json:"sql.mem.client.session.max-p99"`
-		SqlMemClientSessionMaxP999        int     `json:"sql.mem.client.session.max-p99.9"`
-		SqlMemClientSessionMaxP9999       int     `json:"sql.mem.client.session.max-p99.99"`
-		SqlMemClientSessionMaxP99999      int     `json:"sql.mem.client.session.max-p99.999"`
-		SqlMemClientTxnCurrent            int     `json:"sql.mem.client.txn.current"`
-		SqlMemClientTxnMaxMax             int     `json:"sql.mem.client.txn.max-max"`
-		SqlMemClientTxnMaxP50             int     `json:"sql.mem.client.txn.max-p50"`
-		SqlMemClientTxnMaxP75             int     `json:"sql.mem.client.txn.max-p75"`
-		SqlMemClientTxnMaxP90             int     `json:"sql.mem.client.txn.max-p90"`
-		SqlMemClientTxnMaxP99             int     `json:"sql.mem.client.txn.max-p99"`
-		SqlMemClientTxnMaxP999            int     `json:"sql.mem.client.txn.max-p99.9"`
-		SqlMemClientTxnMaxP9999           int     `json:"sql.mem.client.txn.max-p99.99"`
-		SqlMemClientTxnMaxP99999          int     `json:"sql.mem.client.txn.max-p99.999"`
-		SqlMemConnsCurrent                int     `json:"sql.mem.conns.current"`
-		SqlMemConnsMaxMax                 int     `json:"sql.mem.conns.max-max"`
-		SqlMemConnsMaxP50                 int     `json:"sql.mem.conns.max-p50"`
-		SqlMemConnsMaxP75                 int     `json:"sql.mem.conns.max-p75"`
-		SqlMemConnsMaxP90                 int     `json:"sql.mem.conns.max-p90"`
-		SqlMemConnsMaxP99                 int     `json:"sql.mem.conns.max-p99"`
-		SqlMemConnsMaxP999                int     `json:"sql.mem.conns.max-p99.9"`
-		SqlMemConnsMaxP9999               int     `json:"sql.mem.conns.max-p99.99"`
-		SqlMemConnsMaxP99999              int     `json:"sql.mem.conns.max-p99.999"`
-		SqlMemConnsSessionCurrent         int     `json:"sql.mem.conns.session.current"`
-		SqlMemConnsSessionMaxMax          int     `json:"sql.mem.conns.session.max-max"`
-		SqlMemConnsSessionMaxP50          int     `json:"sql.mem.conns.session.max-p50"`
-		SqlMemConnsSessionMaxP75          int     `json:"sql.mem.conns.session.max-p75"`
-		SqlMemConnsSessionMaxP90          int     `json:"sql.mem.conns.session.max-p90"`
-		SqlMemConnsSessionMaxP99          int     `json:"sql.mem.conns.session.max-p99"`
-		SqlMemConnsSessionMaxP999         int     `json:"sql.mem.conns.session.max-p99.9"`
-		SqlMemConnsSessionMaxP9999        int     `json:"sql.mem.conns.session.max-p99.99"`
-		SqlMemConnsSessionMaxP99999       int     `json:"sql.mem.conns.session.max-p99.999"`
-		SqlMemConnsTxnCurrent             int     `json:"sql.mem.conns.txn.current"`
-		SqlMemConnsTxnMaxMax              int     `json:"sql.mem.conns.txn.max-max"`
-		SqlMemConnsTxnMaxP50              int     `json:"sql.mem.conns.txn.max-p50"`
-		SqlMemConnsTxnMaxP75              int     `json:"sql.mem.conns.txn.max-p75"`
-		SqlMemConnsTxnMaxP90              int     `json:"sql.mem.conns.txn.max-p90"`
-		SqlMemConnsTxnMaxP99              int     `json:"sql.mem.conns.txn.max-p99"`
-		SqlMemConnsTxnMaxP999             int     `json:"sql.mem.conns.txn.max-p99.9"`
-		SqlMemConnsTxnMaxP9999            int     `json:"sql.mem.conns.txn.max-p99.99"`
-		SqlMemConnsTxnMaxP99999           int     `json:"sql.mem.conns.txn.max-p99.999"`
-		SqlMemDistsqlCurrent              int     `json:"sql.mem.distsql.current"`
-		SqlMemDistsqlMaxMax               int     `json:"sql.mem.distsql.max-max"`
-		SqlMemDistsqlMaxP50               int     `json:"sql.mem.distsql.max-p50"`
-		SqlMemDistsqlMaxP75               int     `json:"sql.mem.distsql.max-p75"`
-		SqlMemDistsqlMaxP90               int     `json:"sql.mem.distsql.max-p90"`
-		SqlMemDistsqlMaxP99               int     `json:"sql.mem.distsql.max-p99"`
-		SqlMemDistsqlMaxP999              int     `json:"sql.mem.distsql.max-p99.9"`
-		SqlMemDistsqlMaxP9999             int     `json:"sql.mem.distsql.max-p99.99"`
-		SqlMemDistsqlMaxP99999            int     `json:"sql.mem.distsql.max-p99.999"`
-		SqlMemInternalCurrent             int     `json:"sql.mem.internal.current"`
-		SqlMemInternalMaxMax              int     `json:"sql.mem.internal.max-max"`
-		SqlMemInternalMaxP50              int     `json:"sql.mem.internal.max-p50"`
-		SqlMemInternalMaxP75              int     `json:"sql.mem.internal.max-p75"`
-		SqlMemInternalMaxP90              int     `json:"sql.mem.internal.max-p90"`
-		SqlMemInternalMaxP99              int     `json:"sql.mem.internal.max-p99"`
-		SqlMemInternalMaxP999             int     `json:"sql.mem.internal.max-p99.9"`
-		SqlMemInternalMaxP9999            int     `json:"sql.mem.internal.max-p99.99"`
-		SqlMemInternalMaxP99999           int     `json:"sql.mem.internal.max-p99.999"`
-		SqlMemInternalSessionCurrent      int     `json:"sql.mem.internal.session.current"`
-		SqlMemInternalSessionMaxMax       int     `json:"sql.mem.internal.session.max-max"`
-		SqlMemInternalSessionMaxP50       int     `json:"sql.mem.internal.session.max-p50"`
-		SqlMemInternalSessionMaxP75       int     `json:"sql.mem.internal.session.max-p75"`
-		SqlMemInternalSessionMaxP90       int     `json:"sql.mem.internal.session.max-p90"`
-		SqlMemInternalSessionMaxP99       int     `json:"sql.mem.internal.session.max-p99"`
-		SqlMemInternalSessionMaxP999      int     `json:"sql.mem.internal.session.max-p99.9"`
-		SqlMemInternalSessionMaxP9999     int     `json:"sql.mem.internal.session.max-p99.99"`
-		SqlMemInternalSessionMaxP99999    int     `json:"sql.mem.internal.session.max-p99.999"`
-		SqlMemInternalTxnCurrent          int     `json:"sql.mem.internal.txn.current"`
-		SqlMemInternalTxnMaxMax           int     `json:"sql.mem.internal.txn.max-max"`
-		SqlMemInternalTxnMaxP50           int     `json:"sql.mem.internal.txn.max-p50"`
-		SqlMemInternalTxnMaxP75           int     `json:"sql.mem.internal.txn.max-p75"`
-		SqlMemInternalTxnMaxP90           int     `json:"sql.mem.internal.txn.max-p90"`
-		SqlMemInternalTxnMaxP99           int     `json:"sql.mem.internal.txn.max-p99"`
-		SqlMemInternalTxnMaxP999          int     `json:"sql.mem.internal.txn.max-p99.9"`
-		SqlMemInternalTxnMaxP9999         int     `json:"sql.mem.internal.txn.max-p99.99"`
-		SqlMemInternalTxnMaxP99999        int     `json:"sql.mem.internal.txn.max-p99.999"`
-		SqlMiscCount                      int     `json:"sql.misc.count"`
-		SqlQueryCount                     int     `json:"sql.query.count"`
-		SqlSelectCount                    int     `json:"sql.select.count"`
-		SqlServiceLatencyMax              int     `json:"sql.service.latency-max"`
-		SqlServiceLatencyP50              int     `json:"sql.service.latency-p50"`
-		SqlServiceLatencyP75              int     `json:"sql.service.latency-p75"`
-		SqlServiceLatencyP90              int     `json:"sql.service.latency-p90"`
-		SqlServiceLatencyP99              int     `json:"sql.service.latency-p99"`
-		SqlServiceLatencyP999             int     `json:"sql.service.latency-p99.9"`
-		SqlServiceLatencyP9999            int     `json:"sql.service.latency-p99.99"`
-		SqlServiceLatencyP99999           int     `json:"sql.service.latency-p99.999"`
-		SqlTxnAbortCount                  int     `json:"sql.txn.abort.count"`
-		SqlTxnBeginCount                  int     `json:"sql.txn.begin.count"`
-		SqlTxnCommitCount                 int     `json:"sql.txn.commit.count"`
-		SqlTxnRollbackCount               int     `json:"sql.txn.rollback.count"`
-		SqlUpdateCount                    int     `json:"sql.update.count"`
-		SysCgoAllocbytes                  int     `json:"sys.cgo.allocbytes"`
-		SysCgoTotalbytes                  int     `json:"sys.cgo.totalbytes"`
-		SysCgocalls                       int     `json:"sys.cgocalls"`
-		SysCPUSysNs                       int64   `json:"sys.cpu.sys.ns"`
-		SysCPUSysPercent                  float64 `json:"sys.cpu.sys.percent"`
-		SysCPUUserNs                      int64   `json:"sys.cpu.user.ns"`
-		SysCPUUserPercent                 float64 `json:"sys.cpu.user.percent"`
-		SysFdOpen                         int     `json:"sys.fd.open"`
-		SysFdSoftlimit                    int     `json:"sys.fd.softlimit"`
-		SysGcCount                        int     `json:"sys.gc.count"`
-		SysGcPauseNs                      int     `json:"sys.gc.pause.ns"`
-		SysGcPausePercent                 int     `json:"-"`
-		SysGoAllocbytes                   int     `json:"sys.go.allocbytes"`
-		SysGoTotalbytes                   int     `json:"sys.go.totalbytes"`
-		SysGoroutines                     int     `json:"sys.goroutines"`
-		SysRss                            int     `json:"sys.rss"`
-		SysUptime                         int     `json:"sys.uptime"`
-		TimeseriesWriteBytes              int     `json:"timeseries.write.bytes"`
-		TimeseriesWriteErrors             int     `json:"timeseries.write.errors"`
-		TimeseriesWriteSamples            int     `json:"timeseries.write.samples"`
-		TxnAbandons                       int     `json:"txn.abandons"`
-		TxnAborts                         int     `json:"txn.aborts"`
-		TxnAutoretries                    int     `json:"txn.autoretries"`
-		TxnCommits                        int     `json:"txn.commits"`
-		TxnCommits1PC                     int     `json:"txn.commits1PC"`
-		TxnDurationsMax                   int     `json:"txn.durations-max"`
-		TxnDurationsP50                   int     `json:"txn.durations-p50"`
-		TxnDurationsP75                   int     `json:"txn.durations-p75"`
-		TxnDurationsP90                   int     `json:"txn.durations-p90"`
-		TxnDurationsP99                   int     `json:"txn.durations-p99"`
-		TxnDurationsP999                  int     `json:"txn.durations-p99.9"`
-		TxnDurationsP9999                 int     `json:"txn.durations-p99.99"`
-		TxnDurationsP99999                int     `json:"txn.durations-p99.999"`
-		TxnRestartsMax                    int     `json:"txn.restarts-max"`
-		TxnRestartsP50                    int     `json:"txn.restarts-p50"`
-		TxnRestartsP75                    int     `json:"txn.restarts-p75"`
-		TxnRestartsP90                    int     `json:"txn.restarts-p90"`
-		TxnRestartsP99                    int     `json:"txn.restarts-p99"`
-		TxnRestartsP999                   int     `json:"txn.restarts-p99.9"`
-		TxnRestartsP9999                  int     `json:"txn.restarts-p99.99"`
-		TxnRestartsP99999                 int     `json:"txn.restarts-p99.999"`
-		TxnRestartsDeleterange            int     `json:"txn.restarts.deleterange"`
-		TxnRestartsPossiblereplay         int     `json:"txn.restarts.possiblereplay"`
-		TxnRestartsSerializable           int     `json:"txn.restarts.serializable"`
-		TxnRestartsWritetooold            int     `json:"txn.restarts.writetooold"`
-	} `json:"metrics"`
-	StoreStatuses []struct {
-		Desc struct {
-			StoreID int `json:"storeId"`
-			Attrs   struct {
-				Attrs []interface{} `json:"attrs"`
-			} `json:"attrs"`
-			Node struct {
-				NodeID  int `json:"nodeId"`
-				Address struct {
-					NetworkField string `json:"networkField"`
-					AddressField string `json:"addressField"`
-				} `json:"address"`
-				Attrs struct {
-					Attrs []interface{} `json:"attrs"`
-				} `json:"attrs"`
-				Locality struct {
-					Tiers []interface{} `json:"tiers"`
-				} `json:"locality"`
-				ServerVersion struct {
-					MajorVal int `json:"majorVal"`
-					MinorVal int `json:"minorVal"`
-					Patch    int `json:"patch"`
-					Unstable int `json:"unstable"`
-				} `json:"ServerVersion"`
-			} `json:"node"`
-			Capacity struct {
-				Capacity        string  `json:"capacity"`
-				Available       string  `json:"available"`
-				Used            string  `json:"used"`
-				LogicalBytes    string  `json:"logicalBytes"`
-				RangeCount      int     `json:"rangeCount"`
-				LeaseCount      int     `json:"leaseCount"`
-				WritesPerSecond float64 `json:"writesPerSecond"`
-				BytesPerReplica struct {
-					P10  int `json:"-"`
-					P25  int `json:"-"`
-					P50  int `json:"-"`
-					P75  int `json:"-"`
-					P90  int `json:"-"`
-					PMax int `json:"-"`
-				} `json:"bytesPerReplica"`
-				WritesPerReplica struct {
-					P10  int     `json:"-"`
-					P25  int     `json:"-"`
-					P50  int     `json:"-"`
-					P75  float64 `json:"-"`
-					P90  float64 `json:"-"`
-					PMax float64 `json:"-"`
-				} `json:"writesPerReplica"`
-			} `json:"capacity"`
-		} `json:"desc"`
-		Metrics struct {
-			AddsstableApplications                 int     `json:"addsstable.applications"`
-			AddsstableCopies                       int     `json:"addsstable.copies"`
-			AddsstableProposals                    int     `json:"addsstable.proposals"`
-			Capacity                               int64   `json:"capacity"`
-			CapacityAvailable                      int64   `json:"capacity.available"`
-			CapacityReserved                       int     `json:"capacity.reserved"`
-			CapacityUsed                           int     `json:"capacity.used"`
-			CompactorCompactingnanos               int     `json:"compactor.compactingnanos"`
-			CompactorCompactionsFailure            int     `json:"compactor.compactions.failure"`
-			CompactorCompactionsSuccess            int     `json:"compactor.compactions.success"`
-			CompactorSuggestionbytesCompacted      int     `json:"compactor.suggestionbytes.compacted"`
-			CompactorSuggestionbytesQueued         int     `json:"compactor.suggestionbytes.queued"`
-			CompactorSuggestionbytesSkipped        int     `json:"compactor.suggestionbytes.skipped"`
-			Gcbytesage                             int     `json:"gcbytesage"`
-			Intentage                              int     `json:"intentage"`
-			Intentbytes                            int     `json:"intentbytes"`
-			Intentcount                            int     `json:"intentcount"`
-			Keybytes                               int     `json:"keybytes"`
-			Keycount                               int     `json:"keycount"`
-			Lastupdatenanos                        int64   `json:"lastupdatenanos"`
-			LeasesEpoch                            int     `json:"leases.epoch"`
-			LeasesError                            int     `json:"leases.error"`
-			LeasesExpiration                       int     `json:"leases.expiration"`
-			LeasesSuccess                          int     `json:"leases.success"`
-			LeasesTransfersError                   int     `json:"leases.transfers.error"`
-			LeasesTransfersSuccess                 int     `json:"leases.transfers.success"`
-			Livebytes                              int     `json:"livebytes"`
-			Livecount                              int     `json:"livecount"`
-			QueueConsistencyPending                int     `json:"queue.consistency.pending"`
-			QueueConsistencyProcessFailure         int     `json:"queue.consistency.process.failure"`
-			QueueConsistencyProcessSuccess         int     `json:"queue.consistency.process.success"`
-			QueueConsistencyProcessingnanos        int     `json:"queue.consistency.processingnanos"`
-			QueueGcInfoAbortspanconsidered         int     `json:"queue.gc.info.abortspanconsidered"`
-			QueueGcInfoAbortspangcnum              int     `json:"queue.gc.info.abortspangcnum"`
-			QueueGcInfoAbortspanscanned            int     `json:"queue.gc.info.abortspanscanned"`
-			QueueGcInfoIntentsconsidered           int     `json:"queue.gc.info.intentsconsidered"`
-			QueueGcInfoIntenttxns                  int     `json:"queue.gc.info.intenttxns"`
-			QueueGcInfoNumkeysaffected             int     `json:"queue.gc.info.numkeysaffected"`
-			QueueGcInfoPushtxn                     int     `json:"queue.gc.info.pushtxn"`
-			QueueGcInfoResolvesuccess              int     `json:"queue.gc.info.resolvesuccess"`
-			QueueGcInfoResolvetotal                int     `json:"queue.gc.info.resolvetotal"`
-			QueueGcInfoTransactionspangcaborted    int     `json:"queue.gc.info.transactionspangcaborted"`
-			QueueGcInfoTransactionspangccommitted  int     `json:"queue.gc.info.transactionspangccommitted"`
-			QueueGcInfoTransactionspangcpending    int     `json:"queue.gc.info.transactionspangcpending"`
-			QueueGcInfoTransactionspanscanned      int     `json:"queue.gc.info.transactionspanscanned"`
-			QueueGcPending                         int     `json:"queue.gc.pending"`
-			QueueGcProcessFailure                  int     `json:"queue.gc.process.failure"`
-			QueueGcProcessSuccess                  int     `json:"queue.gc.process.success"`
-			QueueGcProcessingnanos                 int     `json:"queue.gc.processingnanos"`
-			QueueRaftlogPending                    int     `json:"queue.raftlog.pending"`
-			QueueRaftlogProcessFailure             int     `json:"queue.raftlog.process.failure"`
-			QueueRaftlogProcessSuccess             int     `json:"queue.raftlog.process.success"`
-			QueueRaftlogProcessingnanos            int64   `json:"queue.raftlog.processingnanos"`
-			QueueRaftsnapshotPending               int     `json:"queue.raftsnapshot.pending"`
-			QueueRaftsnapshotProcessFailure        int     `json:"queue.raftsnapshot.process.failure"`
-			QueueRaftsnapshotProcessSuccess        int     `json:"queue.raftsnapshot.process.success"`
-			QueueRaftsnapshotProcessingnanos       int     `json:"queue.raftsnapshot.processingnanos"`
-			QueueReplicagcPending                  int     `json:"queue.replicagc.pending"`
-			QueueReplicagcProcessFailure           int     `json:"queue.replicagc.process.failure"`
-			QueueReplicagcProcessSuccess           int     `json:"queue.replicagc.process.success"`
-			QueueReplicagcProcessingnanos          int     `json:"queue.replicagc.processingnanos"`
-			QueueReplicagcRemovereplica            int     `json:"queue.replicagc.removereplica"`
-			QueueReplicateAddreplica               int     `json:"queue.replicate.addreplica"`
-			QueueReplicatePending                  int     `json:"queue.replicate.pending"`
-			QueueReplicateProcessFailure           int     `json:"queue.replicate.process.failure"`
-			QueueReplicateProcessSuccess           int     `json:"queue.replicate.process.success"`
-			QueueReplicateProcessingnanos          int     `json:"queue.replicate.processingnanos"`
-			QueueReplicatePurgatory                int     `json:"queue.replicate.purgatory"`
-			QueueReplicateRebalancereplica         int     `json:"queue.replicate.rebalancereplica"`
-			QueueReplicateRemovedeadreplica        int     `json:"queue.replicate.removedeadreplica"`
-			QueueReplicateRemovereplica            int     `json:"queue.replicate.removereplica"`
-			QueueReplicateTransferlease            int     `json:"queue.replicate.transferlease"`
-			QueueSplitPending                      int     `json:"queue.split.pending"`
-			QueueSplitProcessFailure               int     `json:"queue.split.process.failure"`
-			QueueSplitProcessSuccess               int     `json:"queue.split.process.success"`
-			QueueSplitProcessingnanos              int     `json:"queue.split.processingnanos"`
-			QueueTsmaintenancePending              int     `json:"queue.tsmaintenance.pending"`
-			QueueTsmaintenanceProcessFailure       int     `json:"queue.tsmaintenance.process.failure"`
-			QueueTsmaintenanceProcessSuccess       int     `json:"queue.tsmaintenance.process.success"`
-			QueueTsmaintenanceProcessingnanos      int     `json:"queue.tsmaintenance.processingnanos"`
-			RaftCommandsapplied                    int     `json:"raft.commandsapplied"`
-			RaftEnqueuedPending                    int     `json:"raft.enqueued.pending"`
-			RaftHeartbeatsPending                  int     `json:"raft.heartbeats.pending"`
-			RaftProcessCommandcommitLatencyMax     int     `json:"raft.process.commandcommit.latency-max"`
-			RaftProcessCommandcommitLatencyP50     int     `json:"raft.process.commandcommit.latency-p50"`
-			RaftProcessCommandcommitLatencyP75     int     `json:"raft.process.commandcommit.latency-p75"`
-			RaftProcessCommandcommitLatencyP90     int     `json:"raft.process.commandcommit.latency-p90"`
-			RaftProcessCommandcommitLatencyP99     int     `json:"raft.process.commandcommit.latency-p99"`
-			RaftProcessCommandcommitLatencyP999    int     `json:"raft.process.commandcommit.latency-p99.9"`
-			RaftProcessCommandcommitLatencyP9999   int     `json:"raft.process.commandcommit.latency-p99.99"`
-			RaftProcessCommandcommitLatencyP99999  int     `json:"raft.process.commandcommit.latency-p99.999"`
-			RaftProcessLogcommitLatencyMax         int     `json:"raft.process.logcommit.latency-max"`
-			RaftProcessLogcommitLatencyP50         int     `json:"raft.process.logcommit.latency-p50"`
-			RaftProcessLogcommitLatencyP75         int     `json:"raft.process.logcommit.latency-p75"`
-			RaftProcessLogcommitLatencyP90         int     `json:"raft.process.logcommit.latency-p90"`
-			RaftProcessLogcommitLatencyP99         int     `json:"raft.process.logcommit.latency-p99"`
-			RaftProcessLogcommitLatencyP999        int     `json:"raft.process.logcommit.latency-p99.9"`
-			RaftProcessLogcommitLatencyP9999       int     `json:"raft.process.logcommit.latency-p99.99"`
-			RaftProcessLogcommitLatencyP99999      int     `json:"raft.process.logcommit.latency-p99.999"`
-			RaftProcessTickingnanos                int     `json:"raft.process.tickingnanos"`
-			RaftProcessWorkingnanos                int64   `json:"raft.process.workingnanos"`
-			RaftRcvdApp                            int     `json:"raft.rcvd.app"`
-			RaftRcvdAppresp                        int     `json:"raft.rcvd.appresp"`
-			RaftRcvdDropped                        int     `json:"raft.rcvd.dropped"`
-			RaftRcvdHeartbeat                      int     `json:"raft.rcvd.heartbeat"`
-			RaftRcvdHeartbeatresp                  int     `json:"raft.rcvd.heartbeatresp"`
-			RaftRcvdPrevote                        int     `json:"raft.rcvd.prevote"`
-			RaftRcvdPrevoteresp                    int     `json:"raft.rcvd.prevoteresp"`
-			RaftRcvdProp                           int     `json:"raft.rcvd.prop"`
-			RaftRcvdSnap                           int     `json:"raft.rcvd.snap"`
-			RaftRcvdTimeoutnow                     int     `json:"raft.rcvd.timeoutnow"`
-			RaftRcvdTransferleader                 int     `json:"raft.rcvd.transferleader"`
-			RaftRcvdVote                           int     `json:"raft.rcvd.vote"`
-			RaftRcvdVoteresp                       int     `json:"raft.rcvd.voteresp"`
-			RaftTicks                              int     `json:"raft.ticks"`
-			RaftlogBehind                          int     `json:"raftlog.behind"`
-			RaftlogTruncated                       int     `json:"raftlog.truncated"`
-			RangeAdds                              int     `json:"range.adds"`
-			RangeRaftleadertransfers               int     `json:"range.raftleadertransfers"`
-			RangeRemoves                           int     `json:"range.removes"`
-			RangeSnapshotsGenerated                int     `json:"range.snapshots.generated"`
-			RangeSnapshotsNormalApplied            int     `json:"range.snapshots.normal-applied"`
-			RangeSnapshotsPreemptiveApplied        int     `json:"range.snapshots.preemptive-applied"`
-			RangeSplits                            int     `json:"range.splits"`
-			Ranges                                 int     `json:"ranges"`
-			RangesUnavailable                      int     `json:"ranges.unavailable"`
-			RangesUnderreplicated                  int     `json:"ranges.underreplicated"`
-			RebalancingWritespersecond             float64 `json:"rebalancing.writespersecond"`
-			Replicas                               int     `json:"replicas"`
-			ReplicasCommandqueueCombinedqueuesize  int     `json:"replicas.commandqueue.combinedqueuesize"`
-			ReplicasCommandqueueCombinedreadcount  int     `json:"replicas.commandqueue.combinedreadcount"`
-			ReplicasCommandqueueCombinedwritecount int     `json:"replicas.commandqueue.combinedwritecount"`
-			ReplicasCommandqueueMaxoverlaps        int     `json:"replicas.commandqueue.maxoverlaps"`
-			ReplicasCommandqueueMaxreadcount       int     `json:"replicas.commandqueue.maxreadcount"`
-			ReplicasCommandqueueMaxsize            int     `json:"replicas.commandqueue.maxsize"`
-			ReplicasCommandqueueMaxtreesize        int     `json:"replicas.commandqueue.maxtreesize"`
-			ReplicasCommandqueueMaxwritecount      int     `json:"replicas.commandqueue.maxwritecount"`
-			ReplicasLeaders                        int     `json:"replicas.leaders"`
-			ReplicasLeadersNotLeaseholders         int     `json:"replicas.leaders_not_leaseholders"`
-			ReplicasLeaseholders                   int     `json:"replicas.leaseholders"`
-			ReplicasQuiescent                      int     `json:"replicas.quiescent"`
-			ReplicasReserved                       int     `json:"replicas.reserved"`
-			RequestsBackpressureSplit              int     `json:"requests.backpressure.split"`
-			RequestsSlowCommandqueue               int     `json:"requests.slow.commandqueue"`
-			RequestsSlowLease                      int     `json:"requests.slow.lease"`
-			RequestsSlowRaft                       int     `json:"requests.slow.raft"`
-			RocksdbBlockCacheHits                  int     `json:"rocksdb.block.cache.hits"`
-			RocksdbBlockCacheMisses                int     `json:"rocksdb.block.cache.misses"`
-			RocksdbBlockCachePinnedUsage           int     `json:"rocksdb.block.cache.pinned-usage"`
-			RocksdbBlockCacheUsage                 int     `json:"rocksdb.block.cache.usage"`
-			RocksdbBloomFilterPrefixChecked        int     `json:"rocksdb.bloom.filter.prefix.checked"`
-			RocksdbBloomFilterPrefixUseful         int     `json:"rocksdb.bloom.filter.prefix.useful"`
-			RocksdbCompactions                     int     `json:"rocksdb.compactions"`
-			RocksdbFlushes                         int     `json:"rocksdb.flushes"`
-			RocksdbMemtableTotalSize               int     `json:"rocksdb.memtable.total-size"`
-			RocksdbNumSstables                     int     `json:"rocksdb.num-sstables"`
-			RocksdbReadAmplification               int     `json:"rocksdb.read-amplification"`
-			RocksdbTableReadersMemEstimate         int     `json:"rocksdb.table-readers-mem-estimate"`
-			Sysbytes                               int     `json:"sysbytes"`
-			Syscount                               int     `json:"syscount"`
-			Totalbytes                             int     `json:"totalbytes"`
-			TscacheSklReadPages                    int     `json:"tscache.skl.read.pages"`
-			TscacheSklReadRotations                int     `json:"tscache.skl.read.rotations"`
-			TscacheSklWritePages                   int     `json:"tscache.skl.write.pages"`
-			TscacheSklWriteRotations               int     `json:"tscache.skl.write.rotations"`
-			Valbytes                               int     `json:"valbytes"`
-			Valcount                               int     `json:"valcount"`
-		} `json:"metrics"`
-	} `json:"storeStatuses"`
-	Args      []string `json:"args"`
-	Env       []string `json:"env"`
-	Latencies struct {
-	} `json:"latencies"`
-	Activity struct {
-		Num1 struct {
-			Incoming string `json:"incoming"`
-			Outgoing string `json:"outgoing"`
-			Latency  string `json:"latency"`
-		} `json:"1"`
-	} `json:"activity"`
+	StartedAt     jsonInt64     `json:"startedAt"`
+	UpdatedAt     jsonInt64     `json:"updatedAt"`
+	Metrics       Metrics       `json:"metrics"`
+	StoreStatuses []StoreStatus `json:"storeStatuses"`
+	Args          []string      `json:"args"`
+	Env           []string      `json:"env"`
+	// Latencies and Activity are keyed by peer node ID
+	Latencies map[string]jsonInt64       `json:"latencies"`
+	Activity  map[string]NetworkActivity `json:"activity"`
+}
+
+// NetworkActivity is the RPC traffic between a node and one of its peers.
+type NetworkActivity struct {
+	Incoming jsonInt64 `json:"incoming"`
+	Outgoing jsonInt64 `json:"outgoing"`
+	Latency  jsonInt64 `json:"latency"`
+}
+
+// StoreStatus is the status of a single store as reported by its node.
+type StoreStatus struct {
+	Desc struct {
+		StoreID int        `json:"storeId"`
+		Attrs   Attributes `json:"attrs"`
+		Node    struct {
+			NodeID  int `json:"nodeId"`
+			Address struct {
+				NetworkField string `json:"networkField"`
+				AddressField string `json:"addressField"`
+			} `json:"address"`
+			Attrs         Attributes `json:"attrs"`
+			Locality      Locality   `json:"locality"`
+			ServerVersion struct {
+				MajorVal int `json:"majorVal"`
+				MinorVal int `json:"minorVal"`
+				Patch    int `json:"patch"`
+				Unstable int `json:"unstable"`
+			} `json:"ServerVersion"`
+		} `json:"node"`
+		Capacity StoreCapacity `json:"capacity"`
+	} `json:"desc"`
+	Metrics Metrics `json:"metrics"`
+}
+
+// Metrics holds the metrics of a node or store, keyed by CockroachDB metric
+// name. They are decoded without a schema so that metrics added or renamed
+// by later releases are emitted as they are reported.
+type Metrics map[string]interface{}
+
+// UnmarshalJSON decodes every metric as a float64, the type CockroachDB
+// reports them with, so that a metric keeps the same field type whether its
+// value happens to be whole or not. Protobuf JSON writes NaN and infinite
+// values as strings; they, and any other value that is not a finite number,
+// are skipped rather than failing the whole node.
+func (m *Metrics) UnmarshalJSON(b []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var raw map[string]interface{}
+	if err := dec.Decode(&raw); err != nil {
+		return err
+	}
+
+	metrics := make(Metrics, len(raw))
+	for k, value := range raw {
+		var v float64
+		var err error
+		switch value := value.(type) {
+		case json.Number:
+			v, err = value.Float64()
+		case string:
+			v, err = strconv.ParseFloat(value, 64)
+		default:
+			continue
+		}
+		if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+			continue
+		}
+		metrics[k] = v
+	}
+	*m = metrics
+	return nil
+}
+
+// Attributes are the arbitrary attributes of a node or store, such as "ssd".
+type Attributes struct {
+	Attrs []string `json:"attrs"`
+}
+
+// Locality is the ordered list of locality tiers of a node, such as region
+// and zone.
+type Locality struct {
+	Tiers []struct {
+		Key   string `json:"key"`
+		Value string `json:"value"`
+	} `json:"tiers"`
+}
+
+// StoreCapacity is the capacity descriptor of a single store. CockroachDB
+// encodes the 64-bit byte counts as JSON strings.
+type StoreCapacity struct {
+	Capacity         jsonInt64   `json:"capacity"`
+	Available        jsonInt64   `json:"available"`
+	Used             jsonInt64   `json:"used"`
+	LogicalBytes     jsonInt64   `json:"logicalBytes"`
+	RangeCount       int         `json:"rangeCount"`
+	LeaseCount       int         `json:"leaseCount"`
+	WritesPerSecond  float64     `json:"writesPerSecond"`
+	BytesPerReplica  Percentiles `json:"bytesPerReplica"`
+	WritesPerReplica Percentiles `json:"writesPerReplica"`
+}
+
+// Percentiles is the distribution summary CockroachDB reports for per-replica
+// store statistics.
+type Percentiles struct {
+	P10  float64 `json:"p10"`
+	P25  float64 `json:"p25"`
+	P50  float64 `json:"p50"`
+	P75  float64 `json:"p75"`
+	P90  float64 `json:"p90"`
+	PMax float64 `json:"pMax"`
 }
 
 func (c *Cockroachdb) Description() string {
@@ -567,6 +289,163 @@ func (c *Cockroachdb) Description() string {
 var sampleConfig = `
   ## URL of each _status endpoint node in the cluster.
   # servers = ["http://localhost:8080/_status/nodes/1"]
+
+  ## Endpoint style used for servers:
+  ##   status     - JSON node status from the server URL (default)
+  ##   prometheus - Prometheus text from /_status/vars on the server's host
+  # source = "status"
+
+  ## Admin UI URLs used to discover every node of the cluster. The first seed
+  ## that answers is asked for the status of all nodes; the others are only
+  ## tried when it is unreachable. Can be combined with servers.
+  # seeds = ["http://roach1:8080", "http://roach2:8080"]
+
+  ## Metrics are tagged with the cluster_id reported by /_admin/v1/cluster,
+  ## and with cluster_name when set.
+  # cluster_name = ""
+
+  ## Node and store metrics to emit, as glob patterns matched against the
+  ## metric name.
+  ## All metrics are emitted when metric_include is empty.
+  # metric_include = ["sql.*", "sys.*", "liveness.*"]
+  # metric_exclude = ["*-p99.99*"]
+
+  ## Timeout for HTTP requests to the admin UI.
+  # response_timeout = "5s"
+
+  ## Maximum number of servers gathered concurrently.
+  # workers = 5
+
+  ## Number of times a request failing with a connection error or a 5xx
+  ## status code is retried. The wait between attempts starts at
+  ## retry_backoff and doubles with every retry.
+  # retries = 0
+  # retry_backoff = "500ms"
+
+  ## Admin UI credentials of a secure cluster. The plugin logs in through
+  ## /_admin/v1/login and logs in again when its session expires.
+  # username = "telegraf"
+  # password = "secret"
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/telegraf/certs/ca.crt"
+  # tls_cert = "/etc/telegraf/certs/client.telegraf.crt"
+  # tls_key = "/etc/telegraf/certs/client.telegraf.key"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+
+  ## Regroup the flattened -p50 ... -p99.999 and -max fields of each histogram
+  ## into one summary metric per family, with quantile keyed fields.
+  # percentile_summaries = false
+
+  ## Measurement naming of node and store metrics:
+  ##   flat      - every metric is a field of cockroachdb or cockroachdb_store (default)
+  ##   subsystem - metrics are split by the first segment of their name into
+  ##               measurements such as cockroachdb_sql or cockroachdb_store_rocksdb
+  # measurement_naming = "flat"
+
+  ## Normalisation of node and store field keys:
+  ##   ""         - CockroachDB metric names, ex. exec.latency-p99.9 (default)
+  ##   underscore - separators replaced by underscores, ex. exec_latency_p99_9
+  ##   snake_case - as underscore, with camel case words split
+  # field_naming = ""
+
+  ## Report the RPC latency and traffic between every pair of nodes in
+  ## cockroachdb_peer.
+  # peers = false
+
+  ## Report totals computed from every node reached during the gather in
+  ## cockroachdb_cluster: capacity, nodes, versions and clock offsets. With
+  ## expected_nodes set, the number of nodes missing is also reported.
+  ## Requires the default status source.
+  # cluster_rollup = false
+  # expected_nodes = 0
+
+  ## Fill the gaps left by missed gathers, after a restart or a loss of
+  ## connectivity, with the node and store metrics CockroachDB keeps in its
+  ## own time series database. When the previous successful gather is older
+  ## than backfill_gap, the missed window, up to backfill_max_age, is queried
+  ## from /ts/query at backfill_resolution (a multiple of 10s) and added with
+  ## the original timestamps. The time of the last successful gather is kept
+  ## in backfill_state_file to fill the gap of a restart.
+  # backfill = false
+  # backfill_resolution = "10s"
+  # backfill_gap = "1m"
+  # backfill_max_age = "1h"
+  # backfill_state_file = "/var/lib/telegraf/cockroachdb.state"
+
+  ## Cluster-wide measurements are requested from the first seed or server
+  ## that answers.
+  ## Report the liveness of every node and the readiness of the configured
+  ## hosts in cockroachdb_liveness.
+  # liveness = false
+
+  ## Report unavailable, under-replicated, over-replicated and other problem
+  ## ranges per node and per store in cockroachdb_ranges. Up to
+  ## problem_range_limit problem ranges are also listed, one point each.
+  # ranges = false
+  # problem_range_limit = 0
+
+  ## Report every job (backups, restores, schema changes, changefeeds, ...)
+  ## and the number of jobs per type and status in cockroachdb_jobs.
+  # jobs = false
+
+  ## Report the hot_ranges_limit ranges serving the most queries per second
+  ## on each store in cockroachdb_hot_ranges. Set hot_ranges_limit to 0 to
+  ## report every range.
+  # hot_ranges = false
+  # hot_ranges_limit = 10
+
+  ## Report the statistics of the top statements_limit statement fingerprints
+  ## in cockroachdb_statements, ranked by statements_sort_by: count, errors,
+  ## retries, rows, service_latency, max_latency or total_latency. Set
+  ## statements_limit to 0 to report every fingerprint. The query text is
+  ## added as a field with statements_query_text.
+  # statements = false
+  # statements_limit = 20
+  # statements_sort_by = "total_latency"
+  # statements_query_text = false
+
+  ## Report the number of open sessions, active transactions and queries per
+  ## node, application and user in cockroachdb_sessions, with the age of the
+  ## oldest transaction and query. Queries running for longer than
+  ## long_query_threshold are also reported one point each in
+  ## cockroachdb_long_query; they are not reported when it is unset.
+  ## With sessions_local, each seed and server is asked for the sessions of
+  ## its own node instead of the sessions of the whole cluster.
+  # sessions = false
+  # sessions_local = false
+  # long_query_threshold = "1m"
+
+  ## Report every new entry of the cluster event log (node joins and
+  ## restarts, schema, zone config and setting changes) in cockroachdb_event.
+  # events = false
+
+  ## Report the validity of the CA, node, client and UI certificates loaded
+  ## by every node in cockroachdb_certificate.
+  # certificates = false
+
+  ## Report the size of every table in cockroachdb_table. The table
+  ## statistics are expensive to compute, so they are requested at most once
+  ## per table_stats_interval. Databases and tables are selected with glob
+  ## patterns; all of them are reported when the include lists are empty.
+  # tables = false
+  # table_stats_interval = "10m"
+  # database_include = []
+  # database_exclude = ["system"]
+  # table_include = []
+  # table_exclude = []
+
+  ## Additional clusters, each with its own servers, seeds, credentials and
+  ## TLS options; every other option above applies to all of them. Their
+  ## metrics are tagged with cluster_name and with the cluster_id reported
+  ## by /_admin/v1/cluster.
+  # [[inputs.cockroachdb.cluster]]
+  #   name = "production"
+  #   seeds = ["https://prod-roach1:8080", "https://prod-roach2:8080"]
+  #   username = "telegraf"
+  #   password = "secret"
+  #   tls_ca = "/etc/telegraf/certs/prod/ca.crt"
 `
 
 func (c *Cockroachdb) SampleConfig() string {
@@ -575,14 +454,131 @@ func (c *Cockroachdb) SampleConfig() string {
 
 // Reads light stats from all configured servers.
 func (c *Cockroachdb) Gather(acc telegraf.Accumulator) error {
+	if len(c.Cluster) > 0 {
+		c.gatherClusters(acc)
+		if len(c.Servers) == 0 && len(c.Seeds) == 0 {
+			return nil
+		}
+	}
+
 	// Default to a single node at localhost (default adminport)
-	if len(c.Servers) == 0 {
+	if len(c.Servers) == 0 && len(c.Seeds) == 0 {
 		c.Servers = []string{"http://localhost:8080/_status/nodes/1"}
 	}
 
-	// Range over all servers, gathering stats. Returns early in case of any error.
-	for _, s := range c.Servers {
-		acc.AddError(c.gatherNodes(s, acc))
+	if err := c.init(); err != nil {
+		return err
+	}
+	return c.gatherNamedCluster(c.ClusterName, acc)
+}
+
+// init creates the http client and compiles the metric filters on the first
+// gather.
+func (c *Cockroachdb) init() error {
+	if err := c.checkNaming(); err != nil {
+		return err
+	}
+	if err := c.checkBackfill(); err != nil {
+		return err
+	}
+	// The rollup is computed from the JSON node statuses
+	if c.ClusterRollup && c.Source == "prometheus" {
+		return fmt.Errorf("cluster_rollup is not supported with source %q", c.Source)
+	}
+
+	if c.client == nil {
+		client, err := c.createHTTPClient()
+		if err != nil {
+			return err
+		}
+		c.client = client
+	}
+
+	if c.metricFilter == nil {
+		f, err := filter.NewIncludeExcludeFilter(c.MetricInclude, c.MetricExclude)
+		if err != nil {
+			return fmt.Errorf("unable to compile metric filters: %s", err)
+		}
+		c.metricFilter = f
+	}
+	return nil
+}
+
+// gather reads the configured servers and seeds, and the enabled
+// cluster-wide measurements.
+func (c *Cockroachdb) gather(acc telegraf.Accumulator) error {
+	gather := c.gatherNodes
+	switch c.Source {
+	case "", "status":
+	case "prometheus":
+		gather = c.gatherVars
+	default:
+		return fmt.Errorf("unknown source %q", c.Source)
+	}
+
+	start := time.Now()
+	if c.ClusterRollup {
+		c.rollup = newClusterRollup()
+	}
+	if c.Liveness {
+		c.nodeHosts = newNodeHosts()
+	}
+	if c.Backfill {
+		c.backfillSrcs = &backfillSources{}
+	}
+
+	c.gatherServers(gather, acc)
+
+	if len(c.Seeds) > 0 {
+		acc.AddError(c.gatherCluster(acc))
+	}
+
+	if c.Liveness {
+		acc.AddError(c.gatherLiveness(acc))
+	}
+
+	if c.Ranges {
+		acc.AddError(c.gatherProblemRanges(acc))
+	}
+
+	if c.Jobs {
+		acc.AddError(c.gatherJobs(acc))
+	}
+
+	if c.HotRanges {
+		acc.AddError(c.gatherHotRanges(acc))
+	}
+
+	if c.Statements {
+		acc.AddError(c.gatherStatements(acc))
+	}
+
+	if c.Sessions {
+		acc.AddError(c.gatherSessions(acc))
+	}
+
+	if c.Events {
+		acc.AddError(c.gatherEvents(acc))
+	}
+
+	if c.Certificates {
+		acc.AddError(c.gatherCertificates(acc))
+	}
+
+	if c.Tables {
+		acc.AddError(c.gatherTables(acc))
+	}
+
+	if c.SQLAddress != "" {
+		acc.AddError(c.gatherSQL(acc))
+	}
+
+	if c.ClusterRollup {
+		c.gatherRollup(c.rollup, acc)
+	}
+
+	if c.Backfill {
+		acc.AddError(c.gatherBackfill(start, c.backfillSrcs, acc))
 	}
 
 	return nil
@@ -596,10 +592,52 @@ func (c *Cockroachdb) gatherNodes(s string, acc telegraf.Accumulator) error {
 		return fmt.Errorf("Unable to parse given server url %s: %s", s, err)
 	}
 
-	// Perform the GET request on all cockroachdb /_status/nodes/(n) endpoint
-	//resp, err := c.client.Get(s + "/_status/nodes/1")
-	resp, err := c.client.Get(s)
+	// Decode the response JSON into a new status struct
+	stats := &Cockroach{}
+	if err := c.getJSON(s, stats); err != nil {
+		return err
+	}
+
+	c.gatherNode(stats, u.Host, acc)
+	return nil
+}
+
+// gatherCluster asks the first reachable seed for the status of every node
+// in the cluster. Only one seed is used per gather, so a cluster reachable
+// through several seeds is reported once.
+func (c *Cockroachdb) gatherCluster(acc telegraf.Accumulator) error {
+	var errs []string
+	for _, seed := range c.Seeds {
+		u, err := url.Parse(seed)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("unable to parse seed url %s: %s", seed, err))
+			continue
+		}
+
+		var nodes struct {
+			Nodes []*Cockroach `json:"nodes"`
+		}
+		start := time.Now()
+		err = c.getJSON(strings.TrimRight(seed, "/")+nodesPath, &nodes)
+		addScrape(u.Host, time.Since(start), err, acc)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+
+		for _, stats := range nodes.Nodes {
+			c.gatherNode(stats, u.Host, acc)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("no seed reachable: %s", strings.Join(errs, "; "))
+}
 
+// getJSON performs a GET request against address and decodes the JSON
+// response body into v.
+func (c *Cockroachdb) getJSON(address string, v interface{}) error {
+	resp, err := c.get(address)
 	if err != nil {
 		return err
 	}
@@ -607,34 +645,152 @@ func (c *Cockroachdb) gatherNodes(s string, acc telegraf.Accumulator) error {
 
 	// Successful responses will always return status code 200
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("Cockroachdb responded with unexepcted status code %d", resp.StatusCode)
+		return &statusError{
+			msg:  fmt.Sprintf("Cockroachdb responded with unexepcted status code %d from %s", resp.StatusCode, address),
+			code: resp.StatusCode,
+		}
 	}
 
-	// Decode the response JSON into a new lights struct
-	stats := &Cockroach{}
-	if err := json.NewDecoder(resp.Body).Decode(stats); err != nil {
-		return fmt.Errorf("unable to decode Cockroachdb response: %s", err)
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return &decodeError{msg: fmt.Sprintf("unable to decode Cockroachdb response: %s", err)}
+	}
+	return nil
+}
+
+// gatherNode adds the node and store points of a decoded node status.
+func (c *Cockroachdb) gatherNode(stats *Cockroach, server string, acc telegraf.Accumulator) {
+	// Build a map of tags, starting with one tag per locality tier
+	tags := make(map[string]string)
+	for _, tier := range stats.Desc.Locality.Tiers {
+		tags[tier.Key] = tier.Value
+	}
+	if len(stats.Desc.Attrs.Attrs) > 0 {
+		tags["attrs"] = strings.Join(stats.Desc.Attrs.Attrs, ",")
+	}
+	tags["version"] = stats.BuildInfo.Tag
+	if tags["version"] == "" {
+		v := stats.Desc.ServerVersion
+		tags["version"] = fmt.Sprintf("%d.%d-%d", v.MajorVal, v.MinorVal, v.Unstable)
+	}
+	tags["addressField"] = stats.Desc.Address.AddressField
+	tags["node_id"] = strconv.Itoa(stats.Desc.NodeID)
+	tags["server"] = server
+
+	// Build a map of field values from every decoded node metric
+	fields := make(map[string]interface{})
+	for k, v := range stats.Metrics {
+		if c.metricFilter.Match(k) {
+			fields[k] = v
+		}
+	}
+
+	if c.backfillSrcs != nil {
+		c.backfillSrcs.add("cockroachdb", "cr.node.", tags["node_id"], tags, fields)
 	}
 
-	// Build a map of tags
+	// Accumulate the tags and values
+	c.addSummaries(fields, tags, acc)
+	c.addMetrics("cockroachdb", fields, tags, acc)
+
+	if c.Peers {
+		addPeers(stats, tags, acc)
+	}
+
+	if c.rollup != nil {
+		c.rollup.addNode(stats, tags["version"])
+	}
+
+	if c.nodeHosts != nil {
+		c.nodeHosts.add(stats.Desc.NodeID, stats.Desc.Address.AddressField, stats.Desc.HTTPAddress.AddressField)
+	}
+
+	for _, store := range stats.StoreStatuses {
+		c.gatherStore(store, tags, acc)
+	}
+}
+
+// gatherStore adds a cockroachdb_store point built from the capacity
+// descriptor and the metrics of a single store.
+func (c *Cockroachdb) gatherStore(store StoreStatus, nodeTags map[string]string, acc telegraf.Accumulator) {
 	tags := map[string]string{
-		"addressField": stats.Desc.Address.AddressField,
-		"server":       u.Host,
+		"store_id": strconv.Itoa(store.Desc.StoreID),
+	}
+	if len(store.Desc.Attrs.Attrs) > 0 {
+		tags["store_attrs"] = strings.Join(store.Desc.Attrs.Attrs, ",")
+	}
+	for k, v := range nodeTags {
+		tags[k] = v
+	}
+
+	if c.Ranges {
+		addStoreRanges(store.Metrics, tags, acc)
+	}
+
+	fields := make(map[string]interface{})
+	for k, v := range store.Metrics {
+		if c.metricFilter.Match(k) {
+			fields[k] = v
+		}
+	}
+
+	capacity := capacityFields(store.Desc.Capacity)
+	if c.backfillSrcs != nil {
+		// Backfilled metrics take the type of the capacity fields that
+		// replace them
+		backfill := make(map[string]interface{}, len(fields))
+		for k, v := range fields {
+			if cv, ok := capacity[k]; ok {
+				v = cv
+			}
+			backfill[k] = v
+		}
+		c.backfillSrcs.add("cockroachdb_store", "cr.store.", tags["store_id"], tags, backfill)
+	}
+
+	for k, v := range capacity {
+		fields[k] = v
+	}
+
+	c.addSummaries(fields, tags, acc)
+	c.addMetrics("cockroachdb_store", fields, tags, acc)
+}
+
+// addSummaries moves the percentile fields into summary metrics when
+// percentile_summaries is enabled.
+func (c *Cockroachdb) addSummaries(fields map[string]interface{}, tags map[string]string, acc telegraf.Accumulator, t ...time.Time) {
+	if !c.PercentileSummaries {
+		return
 	}
+	for family, quantiles := range splitSummaries(fields) {
+		acc.AddSummary(summaryName(family), quantiles, tags, t...)
+	}
+}
 
-	// Build a map of field values
+// capacityFields converts a store capacity descriptor into numeric fields.
+func capacityFields(c StoreCapacity) map[string]interface{} {
 	fields := map[string]interface{}{
-		"sys.cpu.user.percent":     stats.Metrics.SysCPUUserPercent,
-		"sys.cpu.sys.percent":      stats.Metrics.SysCPUSysPercent,
-		"timeseries.write.bytes":   stats.Metrics.TimeseriesWriteBytes,
-		"timeseries.write.samples": stats.Metrics.TimeseriesWriteSamples,
-		"exec.latency-max":         stats.Metrics.ExecLatencyMax,
+		"capacity":           int64(c.Capacity),
+		"capacity.available": int64(c.Available),
+		"capacity.used":      int64(c.Used),
+		"logicalbytes":       int64(c.LogicalBytes),
+		"rangecount":         c.RangeCount,
+		"leasecount":         c.LeaseCount,
+		"writespersecond":    c.WritesPerSecond,
 	}
 
-	// Accumulate the tags and values
-	acc.AddFields("cockroachdb", fields, tags)
+	for k, p := range map[string]Percentiles{
+		"bytesperreplica":  c.BytesPerReplica,
+		"writesperreplica": c.WritesPerReplica,
+	} {
+		fields[k+"-p10"] = p.P10
+		fields[k+"-p25"] = p.P25
+		fields[k+"-p50"] = p.P50
+		fields[k+"-p75"] = p.P75
+		fields[k+"-p90"] = p.P90
+		fields[k+"-max"] = p.PMax
+	}
 
-	return nil
+	return fields
 }
 
 func init() {
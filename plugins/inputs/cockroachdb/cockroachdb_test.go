@@ -1,16 +1,26 @@
 package cockroachdb
 
 import (
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/influxdata/telegraf/testutil"
 	"github.com/stretchr/testify/require"
 )
 
+var pki = testutil.NewPKI("../../../testutil/pki")
+
 func TestCockroachdb(t *testing.T) {
 	// Create a test server with the const response JSON
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -38,19 +48,2387 @@ func TestCockroachdb(t *testing.T) {
 	expectFields := map[string]interface{}{
 		"sys.cpu.user.percent":     float64(0.004999888952466365),
 		"sys.cpu.sys.percent":      float64(0.010999755695426005),
-		"timeseries.write.bytes":   int(16668854),
-		"timeseries.write.samples": int(169916),
-		"exec.latency-max":         int(6291455),
+		"timeseries.write.bytes":   float64(16668854),
+		"timeseries.write.samples": float64(169916),
+		"exec.latency-max":         float64(6291455),
 	}
 	// Expect the correct values for all tags
 	expectTags := map[string]string{
 		"addressField": "roach1:26257",
+		"node_id":      "1",
+		"server":       u.Host,
+		"version":      "v2.0.3",
+	}
+
+	for k, v := range expectFields {
+		require.True(t, acc.HasPoint("cockroachdb", expectTags, k, v), k)
+	}
+
+	// Every decoded node metric is emitted, not only the fields above
+	require.True(t, acc.HasFloatField("cockroachdb", "sql.txn.abort.count"))
+	require.True(t, acc.HasFloatField("cockroachdb", "sql.mem.admin.max-p99"))
+	require.True(t, acc.HasFloatField("cockroachdb", "liveness.livenodes"))
+}
+
+func TestCockroachdbMetricFilter(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintln(w, response)
+	}))
+	defer ts.Close()
+
+	Cockroachdb := NeCockroachdb()
+	Cockroachdb.Servers = []string{ts.URL}
+	Cockroachdb.MetricInclude = []string{"sys.cpu.*", "sql.txn.*"}
+	Cockroachdb.MetricExclude = []string{"*.ns"}
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, Cockroachdb.Gather(acc))
+
+	acc.AssertContainsFields(t, "cockroachdb", map[string]interface{}{
+		"sys.cpu.user.percent":   float64(0.004999888952466365),
+		"sys.cpu.sys.percent":    float64(0.010999755695426005),
+		"sql.txn.abort.count":    float64(0),
+		"sql.txn.begin.count":    float64(0),
+		"sql.txn.commit.count":   float64(0),
+		"sql.txn.rollback.count": float64(0),
+	})
+}
+
+func TestCockroachdbVersions(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		version string
+		node    map[string]interface{}
+		store   map[string]interface{}
+	}{
+		{
+			name:    "v2.0",
+			body:    response,
+			version: "v2.0.3",
+			node: map[string]interface{}{
+				"sys.cpu.user.percent": float64(0.004999888952466365),
+				"sys.uptime":           float64(18796),
+			},
+			store: map[string]interface{}{
+				"capacity":                  int64(511962286915584),
+				"raft.process.workingnanos": float64(6499028100),
+			},
+		},
+		{
+			name:    "v2.1",
+			body:    responseV21,
+			version: "v2.1.6",
+			node: map[string]interface{}{
+				"sql.txn.latency-p99":         float64(25165823),
+				"sys.gc.pause.percent":        float64(0.00012),
+				"sys.fd.softlimit":            float64(18446744073709551615),
+				"sql.distsql.queries.spilled": float64(3),
+			},
+			store: map[string]interface{}{
+				"capacity":              int64(1000000000000),
+				"ranges.overreplicated": float64(1),
+			},
+		},
+		{
+			name:    "v19.2",
+			body:    responseV192,
+			version: "v19.2.4",
+			node: map[string]interface{}{
+				"sql.conns":                float64(12),
+				"sys.cpu.user.percent":     float64(0.25),
+				"sql.distsql.flows.queued": float64(0),
+				"txn.restarts.txnaborted":  float64(4),
+			},
+			store: map[string]interface{}{
+				"capacity.available":         int64(750000000000),
+				"rocksdb.read-amplification": float64(4),
+				"requests.slow.latch":        float64(0),
+			},
+		},
+		{
+			name:    "v20.2",
+			body:    responseV202,
+			version: "v20.2.7",
+			node: map[string]interface{}{
+				"sys.cpu.combined.percent-normalized": float64(0.0625),
+				"sql.statements.active":               float64(2),
+				"sys.host.disk.read.bytes":            float64(9876543210),
+			},
+			store: map[string]interface{}{
+				"rocksdb.estimated-pending-compaction": float64(0),
+				"rebalancing.queriespersecond":         float64(12.5),
+			},
+		},
+		{
+			name:    "v21.2",
+			body:    responseV212,
+			version: "v21.2.3",
+			node: map[string]interface{}{
+				"admission.admitted.kv":             float64(12345),
+				"sql.txn.contended.count":           float64(8),
+				"sys.host.net.recv.bytes":           float64(123456789),
+				"jobs.changefeed.currently_running": float64(1),
+			},
+			store: map[string]interface{}{
+				"storage.l0-sublevels": float64(0),
+				"ranges.unavailable":   float64(0),
+				"capacity.used":        int64(52428800),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				fmt.Fprintln(w, tt.body)
+			}))
+			defer ts.Close()
+
+			Cockroachdb := NeCockroachdb()
+			Cockroachdb.Servers = []string{ts.URL}
+
+			acc := &testutil.Accumulator{}
+			require.NoError(t, Cockroachdb.Gather(acc))
+			require.NoError(t, acc.FirstError())
+
+			require.Equal(t, tt.version, acc.TagValue("cockroachdb", "version"))
+			node, ok := acc.Get("cockroachdb")
+			require.True(t, ok)
+			for k, v := range tt.node {
+				require.Equal(t, v, node.Fields[k], k)
+			}
+
+			store, ok := acc.Get("cockroachdb_store")
+			require.True(t, ok)
+			for k, v := range tt.store {
+				require.Equal(t, v, store.Fields[k], k)
+			}
+		})
+	}
+}
+
+func TestCockroachdbStableFieldTypes(t *testing.T) {
+	// sys.gc.pause.percent is whole in the first gather and fractional in the
+	// second one
+	body := response
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, body)
+	}))
+	defer ts.Close()
+
+	Cockroachdb := NeCockroachdb()
+	Cockroachdb.Servers = []string{ts.URL}
+	Cockroachdb.MetricInclude = []string{"sys.gc.pause.percent"}
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, Cockroachdb.Gather(acc))
+	body = strings.Replace(response, `"sys.gc.pause.percent": 0,`, `"sys.gc.pause.percent": 0.00012,`, 1)
+	require.NoError(t, Cockroachdb.Gather(acc))
+	require.NoError(t, acc.FirstError())
+
+	var values []interface{}
+	for _, m := range acc.Metrics {
+		if m.Measurement == "cockroachdb" {
+			values = append(values, m.Fields["sys.gc.pause.percent"])
+		}
+	}
+	require.Equal(t, []interface{}{float64(0), float64(0.00012)}, values)
+}
+
+func TestMetricsUnmarshalNonNumbers(t *testing.T) {
+	var status struct {
+		Metrics Metrics `json:"metrics"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(`{"metrics": {"a": 1, "b": "NaN", "c": "Infinity", "d": "-Infinity", "e": "2.5", "f": null, "g": {}}}`), &status))
+	require.Equal(t, Metrics{"a": float64(1), "e": float64(2.5)}, status.Metrics)
+
+	// The node and its stores are still reported
+	body := strings.Replace(response, `"sys.gc.pause.percent": 0,`, `"sys.gc.pause.percent": "NaN",`, 1)
+	require.NotEqual(t, response, body)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, body)
+	}))
+	defer ts.Close()
+
+	Cockroachdb := NeCockroachdb()
+	Cockroachdb.Servers = []string{ts.URL}
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, Cockroachdb.Gather(acc))
+	require.NoError(t, acc.FirstError())
+	require.True(t, acc.HasFloatField("cockroachdb", "sys.uptime"))
+	require.False(t, acc.HasField("cockroachdb", "sys.gc.pause.percent"))
+	require.True(t, acc.HasMeasurement("cockroachdb_store"))
+}
+
+func TestCockroachdbStore(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintln(w, response)
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+
+	Cockroachdb := NeCockroachdb()
+	Cockroachdb.Servers = []string{ts.URL}
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, Cockroachdb.Gather(acc))
+
+	expectTags := map[string]string{
+		"addressField": "roach1:26257",
+		"server":       u.Host,
+		"node_id":      "1",
+		"store_id":     "1",
+		"version":      "v2.0.3",
+	}
+	expectFields := map[string]interface{}{
+		"capacity":                  int64(511962286915584),
+		"capacity.available":        int64(455453737746432),
+		"capacity.used":             int64(55891244),
+		"logicalbytes":              int64(37220469),
+		"rangecount":                int(20),
+		"leasecount":                int(18),
+		"writespersecond":           float64(43.92150548843001),
+		"bytesperreplica-p75":       float64(6278),
+		"writesperreplica-max":      float64(43.13451014260106),
+		"replicas":                  float64(20),
+		"replicas.leaseholders":     float64(18),
+		"rocksdb.compactions":       float64(1),
+		"ranges.underreplicated":    float64(20),
+		"capacity.reserved":         float64(0),
+		"queue.replicate.purgatory": float64(20),
+	}
+	for k, v := range expectFields {
+		require.True(t, acc.HasPoint("cockroachdb_store", expectTags, k, v), k)
+	}
+}
+
+func TestCockroachdbLocalityTags(t *testing.T) {
+	body := strings.Replace(response, "\"tiers\": [\n      ]",
+		`"tiers": [{"key": "region", "value": "us-east1"}, {"key": "zone", "value": "us-east1-b"}]`, 1)
+	body = strings.Replace(body, "\"attrs\": [\n      ]", `"attrs": ["ssd", "mem"]`, 1)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintln(w, body)
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+
+	Cockroachdb := NeCockroachdb()
+	Cockroachdb.Servers = []string{ts.URL}
+	Cockroachdb.MetricInclude = []string{"sys.uptime", "replicas"}
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, Cockroachdb.Gather(acc))
+
+	nodeTags := map[string]string{
+		"addressField": "roach1:26257",
+		"attrs":        "ssd,mem",
+		"node_id":      "1",
+		"region":       "us-east1",
+		"server":       u.Host,
+		"version":      "v2.0.3",
+		"zone":         "us-east1-b",
+	}
+	acc.AssertContainsTaggedFields(t, "cockroachdb",
+		map[string]interface{}{"sys.uptime": float64(18796)}, nodeTags)
+
+	storeTags := map[string]string{"store_id": "1"}
+	for k, v := range nodeTags {
+		storeTags[k] = v
+	}
+	require.True(t, acc.HasPoint("cockroachdb_store", storeTags, "replicas", float64(20)))
+}
+
+func TestCockroachdbPeers(t *testing.T) {
+	body := strings.Replace(response, "\"latencies\": {\n  }",
+		`"latencies": {"2": "1250000", "3": 98000000}`, 1)
+	body = strings.Replace(body, `"1": {
+      "incoming": "0",
+      "outgoing": "0",
+      "latency": "0"
+    }`, `"2": {"incoming": "1024", "outgoing": "2048", "latency": "1300000"},
+    "3": {"incoming": 512, "outgoing": 256, "latency": 0}`, 1)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintln(w, body)
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+
+	Cockroachdb := NeCockroachdb()
+	Cockroachdb.Servers = []string{ts.URL}
+	Cockroachdb.Peers = true
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, Cockroachdb.Gather(acc))
+	require.NoError(t, acc.FirstError())
+
+	tags := func(target string) map[string]string {
+		return map[string]string{
+			"addressField":   "roach1:26257",
+			"node_id":        "1",
+			"server":         u.Host,
+			"target_node_id": target,
+			"version":        "v2.0.3",
+		}
+	}
+	acc.AssertContainsTaggedFields(t, "cockroachdb_peer", map[string]interface{}{
+		"latency":        int64(1250000),
+		"incoming_bytes": int64(1024),
+		"outgoing_bytes": int64(2048),
+	}, tags("2"))
+	acc.AssertContainsTaggedFields(t, "cockroachdb_peer", map[string]interface{}{
+		"latency":        int64(98000000),
+		"incoming_bytes": int64(512),
+		"outgoing_bytes": int64(256),
+	}, tags("3"))
+}
+
+func TestCockroachdbSeeds(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == clusterPath {
+			fmt.Fprint(w, `{"clusterId": "0c5d3e2a-5f1b-4a8e-9d6f-2b7c1e4a9f30"}`)
+			return
+		}
+		require.Equal(t, "/_status/nodes", r.URL.Path)
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, `{"nodes": [%s, %s]}`, response, response)
+	}))
+	defer ts.Close()
+
+	// A seed that is down, followed by two seeds of the same cluster
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	u, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+
+	Cockroachdb := NeCockroachdb()
+	Cockroachdb.Seeds = []string{down.URL, ts.URL, ts.URL + "/"}
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, Cockroachdb.Gather(acc))
+	require.NoError(t, acc.FirstError())
+
+	var nodes int
+	for _, m := range acc.Metrics {
+		if m.Measurement == "cockroachdb" {
+			require.Equal(t, u.Host, m.Tags["server"])
+			require.Equal(t, "0c5d3e2a-5f1b-4a8e-9d6f-2b7c1e4a9f30", m.Tags["cluster_id"])
+			nodes++
+		}
+	}
+	require.Equal(t, 2, nodes)
+}
+
+func TestCockroachdbSeedsUnreachable(t *testing.T) {
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	Cockroachdb := NeCockroachdb()
+	Cockroachdb.Seeds = []string{down.URL}
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, Cockroachdb.Gather(acc))
+	require.Error(t, acc.FirstError())
+	require.False(t, acc.HasMeasurement("cockroachdb"))
+
+	tags := map[string]string{"server": strings.TrimPrefix(down.URL, "http://")}
+	require.True(t, acc.HasPoint("cockroachdb_scrape", tags, "up", 0))
+	require.True(t, acc.HasPoint("cockroachdb_scrape", tags, "error_class", "connection_failed"))
+}
+
+func TestCockroachdbScrape(t *testing.T) {
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintln(w, response)
+	}))
+	defer up.Close()
+
+	unavailable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer unavailable.Close()
+
+	garbage := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, "not json")
+	}))
+	defer garbage.Close()
+
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(1500 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintln(w, response)
+	}))
+	defer slow.Close()
+
+	Cockroachdb := NeCockroachdb()
+	Cockroachdb.Servers = []string{up.URL, unavailable.URL, garbage.URL, slow.URL}
+	Cockroachdb.ResponseTimeout.Duration = time.Second
+	Cockroachdb.Workers = 2
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, Cockroachdb.Gather(acc))
+	require.Len(t, acc.Errors, 3)
+
+	expected := map[string]string{
+		up.URL:          "",
+		unavailable.URL: "http_status",
+		garbage.URL:     "decode",
+		slow.URL:        "timeout",
+	}
+	for server, class := range expected {
+		host := strings.TrimPrefix(server, "http://")
+		found := false
+		for _, m := range acc.Metrics {
+			if m.Measurement != "cockroachdb_scrape" || m.Tags["server"] != host {
+				continue
+			}
+			found = true
+			if class == "" {
+				require.Equal(t, 1, m.Fields["up"])
+				require.NotContains(t, m.Fields, "error_class")
+			} else {
+				require.Equal(t, 0, m.Fields["up"], server)
+				require.Equal(t, class, m.Fields["error_class"], server)
+			}
+			require.IsType(t, float64(0), m.Fields["duration"])
+		}
+		require.True(t, found, server)
+	}
+}
+
+func TestCockroachdbRetries(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == clusterPath {
+			fmt.Fprint(w, `{"clusterId": "0c5d3e2a-5f1b-4a8e-9d6f-2b7c1e4a9f30"}`)
+			return
+		}
+		if atomic.AddInt32(&requests, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintln(w, response)
+	}))
+	defer ts.Close()
+
+	Cockroachdb := NeCockroachdb()
+	Cockroachdb.Servers = []string{ts.URL}
+	Cockroachdb.Retries = 2
+	Cockroachdb.RetryBackoff.Duration = time.Millisecond
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, Cockroachdb.Gather(acc))
+	require.NoError(t, acc.FirstError())
+	require.Equal(t, int32(3), atomic.LoadInt32(&requests))
+	require.True(t, acc.HasMeasurement("cockroachdb"))
+
+	// Without retries left the last status code is reported
+	atomic.StoreInt32(&requests, 0)
+	Cockroachdb.Retries = 1
+
+	acc = &testutil.Accumulator{}
+	require.NoError(t, Cockroachdb.Gather(acc))
+	require.Error(t, acc.FirstError())
+	require.Equal(t, int32(2), atomic.LoadInt32(&requests))
+	require.False(t, acc.HasMeasurement("cockroachdb"))
+}
+
+func TestCockroachdbPrometheus(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == clusterPath {
+			fmt.Fprint(w, `{"clusterId": "0c5d3e2a-5f1b-4a8e-9d6f-2b7c1e4a9f30"}`)
+			return
+		}
+		require.Equal(t, "/_status/vars", r.URL.Path)
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, varsResponse)
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+
+	Cockroachdb := NeCockroachdb()
+	Cockroachdb.Servers = []string{ts.URL + "/_status/nodes/1"}
+	Cockroachdb.Source = "prometheus"
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, Cockroachdb.Gather(acc))
+	require.NoError(t, acc.FirstError())
+
+	nodeTags := map[string]string{
+		"addressField": "roach1:26257",
+		"cluster_id":   "0c5d3e2a-5f1b-4a8e-9d6f-2b7c1e4a9f30",
+		"node_id":      "1",
+		"server":       u.Host,
+	}
+	require.True(t, acc.HasPoint("cockroachdb_sql_conns", nodeTags, "gauge", float64(3)))
+	require.True(t, acc.HasPoint("cockroachdb_sql_txn_abort_count", nodeTags, "counter", float64(7)))
+
+	storeTags := map[string]string{
+		"addressField": "roach1:26257",
+		"cluster_id":   "0c5d3e2a-5f1b-4a8e-9d6f-2b7c1e4a9f30",
+		"node_id":      "1",
 		"server":       u.Host,
+		"store_id":     "1",
+	}
+	require.True(t, acc.HasPoint("cockroachdb_capacity_available", storeTags, "gauge", float64(4.55453737746432e+14)))
+}
+
+func TestCockroachdbPercentileSummaries(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintln(w, response)
+	}))
+	defer ts.Close()
+
+	Cockroachdb := NeCockroachdb()
+	Cockroachdb.Servers = []string{ts.URL}
+	Cockroachdb.PercentileSummaries = true
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, Cockroachdb.Gather(acc))
+
+	acc.AssertContainsFields(t, "cockroachdb_exec_latency", map[string]interface{}{
+		"0.5":     float64(2490367),
+		"0.75":    float64(3538943),
+		"0.9":     float64(4718591),
+		"0.99":    float64(6291455),
+		"0.999":   float64(6291455),
+		"0.9999":  float64(6291455),
+		"0.99999": float64(6291455),
+		"1":       float64(6291455),
+	})
+	require.True(t, acc.HasMeasurement("cockroachdb_sql_mem_admin_max"))
+	require.True(t, acc.HasMeasurement("cockroachdb_raft_process_commandcommit_latency"))
+
+	require.False(t, acc.HasField("cockroachdb", "exec.latency-p50"))
+	require.False(t, acc.HasField("cockroachdb", "exec.latency-max"))
+	require.True(t, acc.HasField("cockroachdb", "exec.success"))
+}
+
+func TestQuantile(t *testing.T) {
+	tests := map[string]string{
+		"p10":     "0.1",
+		"p50":     "0.5",
+		"p99":     "0.99",
+		"p99.9":   "0.999",
+		"p99.999": "0.99999",
+		"max":     "1",
 	}
+	for p, q := range tests {
+		require.Equal(t, q, quantile(p), p)
+	}
+}
+
+func TestCockroachdbSecureLogin(t *testing.T) {
+	var logins int
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/_admin/v1/login":
+			var creds map[string]string
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&creds))
+			if creds["username"] != "telegraf" || creds["password"] != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			logins++
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: fmt.Sprint(logins), Path: "/"})
+			fmt.Fprintln(w, "{}")
+		default:
+			// Expire the first session to force a second login
+			cookie, err := r.Cookie("session")
+			if err != nil || cookie.Value != "2" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprintln(w, response)
+		}
+	}))
+	tlsConfig, err := pki.TLSServerConfig().TLSConfig()
+	require.NoError(t, err)
+	ts.TLS = tlsConfig
+	ts.StartTLS()
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+
+	Cockroachdb := NeCockroachdb()
+	Cockroachdb.Servers = []string{ts.URL + "/_status/nodes/1"}
+	Cockroachdb.ClientConfig = *pki.TLSClientConfig()
+	Cockroachdb.Username = "telegraf"
+	Cockroachdb.Password = "secret"
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, Cockroachdb.Gather(acc))
+	require.Error(t, acc.FirstError())
+
+	acc = &testutil.Accumulator{}
+	require.NoError(t, Cockroachdb.Gather(acc))
+	require.NoError(t, acc.FirstError())
+	require.Equal(t, 2, logins)
+	require.Equal(t, u.Host, acc.TagValue("cockroachdb", "server"))
+}
+
+func TestCockroachdbSecureWithoutCredentials(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer ts.Close()
+
+	Cockroachdb := NeCockroachdb()
+	Cockroachdb.Servers = []string{ts.URL}
+	Cockroachdb.InsecureSkipVerify = true
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, Cockroachdb.Gather(acc))
+	require.Error(t, acc.FirstError())
+}
+
+func TestCockroachdbLiveness(t *testing.T) {
+	var ready, notReadyHost string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/_admin/v1/liveness":
+			fmt.Fprint(w, livenessResponseJSON)
+		case "/_status/nodes":
+			fmt.Fprintf(w, `{"nodes": [{"desc": {"nodeId": 1, "httpAddress": {"addressField": %q}}}, {"desc": {"nodeId": 2, "httpAddress": {"addressField": %q}}}]}`,
+				ready, notReadyHost)
+		case "/health":
+			require.Equal(t, "1", r.URL.Query().Get("ready"))
+			fmt.Fprint(w, `{"nodeId": 1}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	// A second node that is not ready answers 503 without its node ID
+	var probes int32
+	notReady := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" {
+			atomic.AddInt32(&probes, 1)
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprint(w, `{"error": "node is not ready", "code": 14}`)
+	}))
+	defer notReady.Close()
 
-	acc.AssertContainsTaggedFields(t, "cockroachdb", expectFields, expectTags)
+	// A host that does not answer and shares its host name with both nodes
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	u, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+	n, err := url.Parse(notReady.URL)
+	require.NoError(t, err)
+	d, err := url.Parse(down.URL)
+	require.NoError(t, err)
+	ready, notReadyHost = u.Host, n.Host
+
+	Cockroachdb := NeCockroachdb()
+	Cockroachdb.Seeds = []string{ts.URL}
+	Cockroachdb.Servers = []string{notReady.URL + "/_status/nodes/2", down.URL + "/_status/nodes/3"}
+	Cockroachdb.MetricInclude = []string{"none"}
+	Cockroachdb.Liveness = true
+	Cockroachdb.Retries = 2
+	Cockroachdb.RetryBackoff.Duration = time.Millisecond
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, Cockroachdb.Gather(acc))
+
+	// Readiness is probed once, without retries
+	require.Equal(t, int32(1), atomic.LoadInt32(&probes))
+
+	acc.AssertContainsTaggedFields(t, "cockroachdb_liveness", map[string]interface{}{
+		"state":           "live",
+		"epoch":           int64(3),
+		"expiration":      int64(1530594698327860200),
+		"draining":        false,
+		"decommissioning": false,
+		"ready":           true,
+	}, map[string]string{"node_id": "1", "server": u.Host})
+	acc.AssertContainsTaggedFields(t, "cockroachdb_liveness", map[string]interface{}{
+		"state":           "suspect",
+		"epoch":           int64(7),
+		"expiration":      int64(1530594680000000000),
+		"draining":        false,
+		"decommissioning": false,
+		"ready":           false,
+	}, map[string]string{"node_id": "2", "server": u.Host})
+	acc.AssertContainsTaggedFields(t, "cockroachdb_liveness", map[string]interface{}{
+		"state":           "decommissioning",
+		"epoch":           int64(1),
+		"expiration":      int64(1530594600000000000),
+		"draining":        true,
+		"decommissioning": true,
+	}, map[string]string{"node_id": "3", "server": u.Host})
+	acc.AssertContainsTaggedFields(t, "cockroachdb_liveness",
+		map[string]interface{}{"ready": false}, map[string]string{"server": d.Host})
+}
+
+func TestNodeHosts(t *testing.T) {
+	hosts := newNodeHosts()
+	hosts.add(1, "roach1:26257", "")
+	hosts.add(2, "roach2:26257", "roach2:8080")
+	hosts.add(3, "shared:26257", "shared:8081")
+	hosts.add(4, "shared:26258", "shared:8082")
+
+	for host, expected := range map[string]int{
+		"roach1:8080": 1,
+		"roach2:8080": 2,
+		"roach2:9999": 2,
+		"shared:8082": 4,
+		"shared:8083": 0,
+		"roach9:8080": 0,
+	} {
+		id, ok := hosts.nodeID(host)
+		require.Equal(t, expected != 0, ok, host)
+		require.Equal(t, expected, id, host)
+	}
 }
 
+func TestLivenessState(t *testing.T) {
+	require.Equal(t, "live", livenessState([]byte(`"LIVE"`), false, false))
+	require.Equal(t, "live", livenessState([]byte(`3`), false, false))
+	require.Equal(t, "draining", livenessState([]byte(`"NODE_STATUS_LIVE"`), true, false))
+	require.Equal(t, "dead", livenessState([]byte(`1`), false, false))
+	require.Equal(t, "decommissioned", livenessState([]byte(`"DECOMMISSIONED"`), false, true))
+	require.Equal(t, "unknown", livenessState(nil, false, false))
+}
+
+func TestCockroachdbRanges(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/_status/problemranges":
+			fmt.Fprint(w, problemRangesResponseJSON)
+		default:
+			fmt.Fprintln(w, response)
+		}
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+
+	Cockroachdb := NeCockroachdb()
+	Cockroachdb.Servers = []string{ts.URL + "/_status/nodes/1"}
+	Cockroachdb.Ranges = true
+	Cockroachdb.ProblemRangeLimit = 3
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, Cockroachdb.Gather(acc))
+	require.NoError(t, acc.FirstError())
+
+	acc.AssertContainsTaggedFields(t, "cockroachdb_ranges", map[string]interface{}{
+		"unavailable":                 float64(1),
+		"underreplicated":             float64(2),
+		"overreplicated":              float64(0),
+		"no_raft_leader":              float64(0),
+		"no_lease":                    float64(1),
+		"raft_leader_not_leaseholder": float64(0),
+		"quiescent_equals_ticking":    float64(0),
+		"raft_log_too_large":          float64(0),
+	}, map[string]string{"node_id": "1", "server": u.Host})
+	acc.AssertContainsTaggedFields(t, "cockroachdb_ranges", map[string]interface{}{
+		"unavailable":                 float64(0),
+		"underreplicated":             float64(0),
+		"overreplicated":              float64(0),
+		"no_raft_leader":              float64(0),
+		"no_lease":                    float64(0),
+		"raft_leader_not_leaseholder": float64(0),
+		"quiescent_equals_ticking":    float64(0),
+		"raft_log_too_large":          float64(0),
+		"error":                       "rpc error: node unavailable",
+	}, map[string]string{"node_id": "2", "server": u.Host})
+
+	// Problem ranges are listed up to the limit
+	for _, p := range []struct{ problem, rangeID string }{
+		{"unavailable", "42"},
+		{"underreplicated", "7"},
+		{"underreplicated", "8"},
+	} {
+		tags := map[string]string{"node_id": "1", "server": u.Host, "problem": p.problem, "range_id": p.rangeID}
+		require.True(t, acc.HasPoint("cockroachdb_ranges", tags, "problem_range", 1), p.rangeID)
+	}
+	require.False(t, acc.HasPoint("cockroachdb_ranges",
+		map[string]string{"node_id": "1", "server": u.Host, "problem": "no_lease", "range_id": "42"}, "problem_range", 1))
+	for _, m := range acc.Metrics {
+		if m.Measurement == "cockroachdb_ranges" && m.Tags["range_id"] != "" {
+			require.NotContains(t, m.Fields, "ranges")
+		}
+	}
+
+	// Store range metrics come from the node status
+	storeTags := map[string]string{
+		"addressField": "roach1:26257",
+		"node_id":      "1",
+		"server":       u.Host,
+		"store_id":     "1",
+		"version":      "v2.0.3",
+	}
+	acc.AssertContainsTaggedFields(t, "cockroachdb_ranges", map[string]interface{}{
+		"ranges":          float64(20),
+		"unavailable":     float64(0),
+		"underreplicated": float64(20),
+		"quiescent":       float64(20),
+	}, storeTags)
+
+	// Node and store points share their field types
+	types := make(map[string]string)
+	for _, m := range acc.Metrics {
+		if m.Measurement != "cockroachdb_ranges" {
+			continue
+		}
+		for k, v := range m.Fields {
+			if typ, ok := types[k]; ok {
+				require.Equal(t, typ, fmt.Sprintf("%T", v), k)
+			}
+			types[k] = fmt.Sprintf("%T", v)
+		}
+	}
+}
+
+func TestCockroachdbJobs(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/_admin/v1/jobs":
+			fmt.Fprint(w, jobsResponseJSON)
+		default:
+			fmt.Fprint(w, `{"nodes": []}`)
+		}
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+
+	Cockroachdb := NeCockroachdb()
+	Cockroachdb.Seeds = []string{ts.URL}
+	Cockroachdb.MetricInclude = []string{"none"}
+	Cockroachdb.Jobs = true
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, Cockroachdb.Gather(acc))
+
+	backup := map[string]string{"job_id": "364829124012032001", "type": "BACKUP", "status": "succeeded", "server": u.Host}
+	require.True(t, acc.HasPoint("cockroachdb_jobs", backup, "running_seconds", float64(1800)))
+	require.True(t, acc.HasPoint("cockroachdb_jobs", backup, "fraction_completed", float64(1)))
+
+	restore := map[string]string{"job_id": "364829124012032002", "type": "RESTORE", "status": "failed", "server": u.Host}
+	require.True(t, acc.HasPoint("cockroachdb_jobs", restore, "error", "file does not exist"))
+
+	changefeed := map[string]string{"job_id": "364829124012032003", "type": "CHANGEFEED", "status": "running", "server": u.Host}
+	require.True(t, acc.HasPoint("cockroachdb_jobs", changefeed, "fraction_completed", float64(0)))
+	for _, m := range acc.Metrics {
+		if m.Tags["job_id"] == "364829124012032003" {
+			require.True(t, m.Fields["highwater_lag_seconds"].(float64) > 0)
+			require.True(t, m.Fields["running_seconds"].(float64) > m.Fields["highwater_lag_seconds"].(float64))
+		}
+	}
+
+	require.True(t, acc.HasPoint("cockroachdb_jobs",
+		map[string]string{"type": "BACKUP", "status": "succeeded", "server": u.Host}, "jobs", 2))
+	require.True(t, acc.HasPoint("cockroachdb_jobs",
+		map[string]string{"type": "CHANGEFEED", "status": "running", "server": u.Host}, "jobs", 1))
+}
+
+type fakeRow []interface{}
+
+func (r fakeRow) Scan(dest ...interface{}) error {
+	for i, v := range r {
+		*dest[i].(*interface{}) = v
+	}
+	return nil
+}
+
+func TestCockroachdbStatements(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/_status/statements":
+			fmt.Fprint(w, statementsResponseJSON)
+		default:
+			fmt.Fprint(w, `{"nodes": []}`)
+		}
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+
+	Cockroachdb := NeCockroachdb()
+	Cockroachdb.Seeds = []string{ts.URL}
+	Cockroachdb.MetricInclude = []string{"none"}
+	Cockroachdb.Statements = true
+	Cockroachdb.StatementsLimit = 2
+	Cockroachdb.StatementsQueryText = true
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, Cockroachdb.Gather(acc))
+	require.NoError(t, acc.FirstError())
+
+	selectQuery := "SELECT * FROM users WHERE id = $1"
+	insertQuery := "INSERT INTO orders VALUES ($1, $2)"
+	updateQuery := "UPDATE stock SET n = n - _ WHERE id = $1"
+
+	var fingerprints []string
+	for _, m := range acc.Metrics {
+		if m.Measurement != "cockroachdb_statements" {
+			continue
+		}
+		fingerprints = append(fingerprints, m.Tags["fingerprint"])
+		require.Equal(t, "shop", m.Tags["app"])
+		require.Equal(t, "shop", m.Tags["database"])
+		require.Equal(t, u.Host, m.Tags["server"])
+
+		if m.Fields["query"] == selectQuery {
+			require.Equal(t, int64(155), m.Fields["count"])
+			require.Equal(t, int64(5), m.Fields["errors"])
+			require.Equal(t, int64(2), m.Fields["retries"])
+			require.Equal(t, int64(1), m.Fields["max_retries"])
+			require.InDelta(t, 150.0/155, m.Fields["rows_mean"], 1e-9)
+			require.InDelta(t, 0.45/155, m.Fields["service_latency_mean"], 1e-9)
+			require.Equal(t, 0.08, m.Fields["service_latency_max"])
+		}
+	}
+	require.Equal(t, []string{fingerprintHash(selectQuery), fingerprintHash(insertQuery)}, fingerprints)
+	require.NotEqual(t, fingerprintHash(selectQuery), fingerprintHash(updateQuery))
+
+	// Without query text, ranked by count
+	Cockroachdb.StatementsSortBy = "count"
+	Cockroachdb.StatementsLimit = 1
+	Cockroachdb.StatementsQueryText = false
+
+	acc = &testutil.Accumulator{}
+	require.NoError(t, Cockroachdb.Gather(acc))
+	require.NoError(t, acc.FirstError())
+	require.True(t, acc.HasPoint("cockroachdb_statements",
+		map[string]string{"app": "shop", "database": "shop", "fingerprint": fingerprintHash(selectQuery), "server": u.Host},
+		"count", int64(155)))
+	require.False(t, acc.HasField("cockroachdb_statements", "query"))
+
+	Cockroachdb.StatementsSortBy = "latency"
+	acc = &testutil.Accumulator{}
+	require.NoError(t, Cockroachdb.Gather(acc))
+	require.Error(t, acc.FirstError())
+}
+
+func TestCockroachdbSessions(t *testing.T) {
+	now := time.Now().UTC()
+	ago := func(d time.Duration) string {
+		return now.Add(-d).Format(time.RFC3339Nano)
+	}
+	body := fmt.Sprintf(sessionsResponseJSON,
+		ago(time.Hour), ago(3*time.Hour), ago(2*time.Hour),
+		ago(time.Second), ago(time.Minute), ago(10*time.Second))
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/_status/sessions":
+			fmt.Fprint(w, body)
+		default:
+			fmt.Fprint(w, `{"nodes": []}`)
+		}
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+
+	Cockroachdb := NeCockroachdb()
+	Cockroachdb.Seeds = []string{ts.URL}
+	Cockroachdb.MetricInclude = []string{"none"}
+	Cockroachdb.Sessions = true
+	Cockroachdb.LongQueryThreshold.Duration = 30 * time.Minute
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, Cockroachdb.Gather(acc))
+	require.EqualError(t, acc.FirstError(), "sessions of node 3: node unavailable")
+
+	shop := map[string]string{"node_id": "1", "app": "shop", "user": "shop", "server": u.Host}
+	require.True(t, acc.HasPoint("cockroachdb_sessions", shop, "sessions", 2))
+	require.True(t, acc.HasPoint("cockroachdb_sessions", shop, "transactions", 1))
+	require.True(t, acc.HasPoint("cockroachdb_sessions", shop, "queries", 2))
+
+	idle := map[string]string{"node_id": "2", "app": "", "user": "root", "server": u.Host}
+	require.True(t, acc.HasPoint("cockroachdb_sessions", idle, "sessions", 1))
+	require.True(t, acc.HasPoint("cockroachdb_sessions", idle, "queries", 0))
+
+	for _, m := range acc.Metrics {
+		switch m.Measurement {
+		case "cockroachdb_sessions":
+			if m.Tags["node_id"] == "1" {
+				require.InDelta(t, (2 * time.Hour).Seconds(), m.Fields["oldest_transaction_seconds"], 60)
+				require.InDelta(t, time.Hour.Seconds(), m.Fields["oldest_query_seconds"], 60)
+			} else {
+				require.NotContains(t, m.Fields, "oldest_transaction_seconds")
+				require.NotContains(t, m.Fields, "oldest_query_seconds")
+			}
+		case "cockroachdb_long_query":
+			require.Equal(t, "15f3c9a8e3b5a0c80000000000000001", m.Tags["query_id"])
+			require.Equal(t, "EXECUTING", m.Tags["phase"])
+			require.Equal(t, "SELECT * FROM orders FOR UPDATE", m.Fields["query"])
+			require.Equal(t, true, m.Fields["distributed"])
+			require.InDelta(t, time.Hour.Seconds(), m.Fields["running_seconds"], 60)
+		}
+	}
+
+	var long int
+	for _, m := range acc.Metrics {
+		if m.Measurement == "cockroachdb_long_query" {
+			long++
+		}
+	}
+	require.Equal(t, 1, long)
+}
+
+func TestCockroachdbLocalSessions(t *testing.T) {
+	local := func(nodeID int) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/_status/local_sessions":
+				fmt.Fprintf(w, `{"sessions": [{"nodeId": %d, "username": "root", "applicationName": "shop"}]}`, nodeID)
+			case "/_status/sessions":
+				t.Errorf("cluster-wide sessions requested from node %d", nodeID)
+			default:
+				fmt.Fprint(w, `{"nodes": []}`)
+			}
+		}))
+	}
+	roach1 := local(1)
+	defer roach1.Close()
+	roach2 := local(2)
+	defer roach2.Close()
+
+	Cockroachdb := NeCockroachdb()
+	Cockroachdb.Seeds = []string{roach1.URL, roach2.URL}
+	Cockroachdb.MetricInclude = []string{"none"}
+	Cockroachdb.Sessions = true
+	Cockroachdb.SessionsLocal = true
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, Cockroachdb.Gather(acc))
+	require.NoError(t, acc.FirstError())
+
+	for id, server := range map[string]string{"1": roach1.URL, "2": roach2.URL} {
+		tags := map[string]string{"node_id": id, "app": "shop", "user": "root", "server": strings.TrimPrefix(server, "http://")}
+		require.True(t, acc.HasPoint("cockroachdb_sessions", tags, "sessions", 1), id)
+	}
+}
+
+func TestCockroachdbHotRanges(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/_status/hotranges":
+			fmt.Fprint(w, hotRangesResponseJSON)
+		default:
+			fmt.Fprint(w, `{"nodes": []}`)
+		}
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+
+	Cockroachdb := NeCockroachdb()
+	Cockroachdb.Seeds = []string{ts.URL}
+	Cockroachdb.MetricInclude = []string{"none"}
+	Cockroachdb.HotRanges = true
+	Cockroachdb.HotRangesLimit = 2
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, Cockroachdb.Gather(acc))
+	require.EqualError(t, acc.FirstError(), "hot ranges of node 3: rpc error: node unavailable")
+
+	acc.AssertContainsTaggedFields(t, "cockroachdb_hot_ranges",
+		map[string]interface{}{"queries_per_second": float64(950.5)},
+		map[string]string{"range_id": "84", "store_id": "1", "node_id": "1", "server": u.Host})
+	acc.AssertContainsTaggedFields(t, "cockroachdb_hot_ranges",
+		map[string]interface{}{"queries_per_second": float64(120)},
+		map[string]string{"range_id": "42", "store_id": "1", "node_id": "1", "server": u.Host})
+	acc.AssertContainsTaggedFields(t, "cockroachdb_hot_ranges",
+		map[string]interface{}{"queries_per_second": float64(300)},
+		map[string]string{"range_id": "97", "store_id": "2", "node_id": "2", "server": u.Host,
+			"database": "shop", "table": "orders", "index": "orders_pkey"})
+
+	var ranges []string
+	for _, m := range acc.Metrics {
+		if m.Measurement == "cockroachdb_hot_ranges" {
+			ranges = append(ranges, m.Tags["range_id"])
+		}
+	}
+	require.Len(t, ranges, 3)
+	require.NotContains(t, ranges, "7")
+}
+
+func TestCockroachdbTables(t *testing.T) {
+	var requested []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Path)
+		switch r.URL.Path {
+		case "/_admin/v1/databases":
+			fmt.Fprint(w, `{"databases": ["analytics", "shop", "system"]}`)
+		case "/_admin/v1/databases/shop":
+			fmt.Fprint(w, `{"grants": [], "tableNames": ["audit_log", "orders"], "descriptorId": "52"}`)
+		case "/_admin/v1/databases/analytics":
+			fmt.Fprint(w, `{"grants": [], "tableNames": ["events"], "descriptorId": "53"}`)
+		case "/_admin/v1/databases/shop/tables/orders/stats":
+			fmt.Fprint(w, tableStatsResponseJSON)
+		case "/_admin/v1/databases/analytics/tables/events/stats":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			fmt.Fprint(w, `{"nodes": []}`)
+		}
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+
+	Cockroachdb := NeCockroachdb()
+	Cockroachdb.Seeds = []string{ts.URL}
+	Cockroachdb.MetricInclude = []string{"none"}
+	Cockroachdb.Tables = true
+	Cockroachdb.DatabaseExclude = []string{"system"}
+	Cockroachdb.TableExclude = []string{"audit_*"}
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, Cockroachdb.Gather(acc))
+	require.Error(t, acc.FirstError())
+
+	acc.AssertContainsTaggedFields(t, "cockroachdb_table",
+		map[string]interface{}{
+			"range_count":            int64(3),
+			"replica_count":          int64(9),
+			"node_count":             int64(3),
+			"approximate_disk_bytes": int64(1048576),
+			"live_bytes":             int64(524288),
+			"missing_nodes":          1,
+		},
+		map[string]string{"database": "shop", "table": "orders", "server": u.Host})
+	require.NotContains(t, requested, "/_admin/v1/databases/system")
+	require.NotContains(t, requested, "/_admin/v1/databases/shop/tables/audit_log/stats")
+
+	// The statistics are not requested again before table_stats_interval
+	requested = nil
+	acc = &testutil.Accumulator{}
+	require.NoError(t, Cockroachdb.Gather(acc))
+	require.False(t, acc.HasMeasurement("cockroachdb_table"))
+	require.NotContains(t, requested, "/_admin/v1/databases")
+
+	Cockroachdb.TableStatsInterval.Duration = 0
+	acc = &testutil.Accumulator{}
+	require.NoError(t, Cockroachdb.Gather(acc))
+	require.True(t, acc.HasMeasurement("cockroachdb_table"))
+}
+
+func TestCockroachdbEvents(t *testing.T) {
+	events := []string{
+		`{"timestamp": "2018-09-03T10:05:00.5Z", "eventType": "set_cluster_setting", "targetId": "0", "reportingId": "1", "info": "{\"SettingName\":\"kv.range_merge.queue_enabled\",\"Value\":\"true\"}", "uniqueId": "AQ=="}`,
+		`{"timestamp": "2018-09-03T10:00:00Z", "eventType": "node_join", "targetId": "2", "reportingId": "2", "info": "{}", "uniqueId": "Ag=="}`,
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/_admin/v1/events":
+			fmt.Fprintf(w, `{"events": [%s]}`, strings.Join(events, ","))
+		default:
+			fmt.Fprint(w, `{"nodes": []}`)
+		}
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+
+	Cockroachdb := NeCockroachdb()
+	Cockroachdb.Seeds = []string{ts.URL}
+	Cockroachdb.MetricInclude = []string{"none"}
+	Cockroachdb.Events = true
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, Cockroachdb.Gather(acc))
+	require.NoError(t, acc.FirstError())
+
+	setting := map[string]string{"event_type": "set_cluster_setting", "target_id": "0", "reporting_id": "1", "server": u.Host}
+	require.True(t, acc.HasPoint("cockroachdb_event", setting,
+		"info", `{"SettingName":"kv.range_merge.queue_enabled","Value":"true"}`))
+	require.True(t, acc.HasPoint("cockroachdb_event",
+		map[string]string{"event_type": "node_join", "target_id": "2", "reporting_id": "2", "server": u.Host}, "info", "{}"))
+	for _, m := range acc.Metrics {
+		if m.Tags["event_type"] == "node_join" {
+			require.Equal(t, time.Date(2018, 9, 3, 10, 0, 0, 0, time.UTC), m.Time.UTC())
+		}
+	}
+
+	// Only the events logged since are reported by the next gather
+	events = append([]string{
+		`{"timestamp": "2018-09-03T10:10:00Z", "eventType": "node_restart", "targetId": "3", "reportingId": "3", "info": "{}", "uniqueId": "Aw=="}`,
+		`{"timestamp": "2018-09-03T10:05:00.5Z", "eventType": "create_table", "targetId": "53", "reportingId": "1", "info": "{}", "uniqueId": "BA=="}`,
+	}, events...)
+
+	acc = &testutil.Accumulator{}
+	require.NoError(t, Cockroachdb.Gather(acc))
+	require.NoError(t, acc.FirstError())
+
+	var types []string
+	for _, m := range acc.Metrics {
+		if m.Measurement == "cockroachdb_event" {
+			types = append(types, m.Tags["event_type"])
+		}
+	}
+	require.Equal(t, []string{"node_restart", "create_table"}, types)
+
+	acc = &testutil.Accumulator{}
+	require.NoError(t, Cockroachdb.Gather(acc))
+	require.False(t, acc.HasMeasurement("cockroachdb_event"))
+}
+
+func TestCockroachdbCertificates(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/_status/nodes":
+			fmt.Fprint(w, `{"nodes": [{"desc": {"nodeId": 1}}, {"desc": {"nodeId": 2}}, {"desc": {"nodeId": 3}}]}`)
+		case "/_status/certificates/1":
+			fmt.Fprint(w, certificatesResponseJSON)
+		case "/_status/certificates/2":
+			fmt.Fprint(w, `{"certificates": [{"type": "CLIENT", "errorMessage": "open certs/client.root.key: permission denied"}]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+
+	Cockroachdb := NeCockroachdb()
+	Cockroachdb.Servers = []string{ts.URL}
+	Cockroachdb.Source = "prometheus"
+	Cockroachdb.Certificates = true
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, Cockroachdb.Gather(acc))
+
+	ca := map[string]string{"node_id": "1", "type": "ca", "server": u.Host}
+	require.True(t, acc.HasPoint("cockroachdb_certificate", ca, "status", "ok"))
+	require.True(t, acc.HasPoint("cockroachdb_certificate", ca, "not_before", int64(1514764800)))
+	require.True(t, acc.HasPoint("cockroachdb_certificate", ca, "not_after", int64(1893456000)))
+
+	node := map[string]string{"node_id": "1", "type": "node", "server": u.Host}
+	require.True(t, acc.HasPoint("cockroachdb_certificate", node, "status", "expired"))
+	require.True(t, acc.HasPoint("cockroachdb_certificate", node, "not_after", int64(1546300800)))
+
+	for _, m := range acc.Metrics {
+		if m.Measurement != "cockroachdb_certificate" {
+			continue
+		}
+		switch m.Tags["type"] {
+		case "ca":
+			require.True(t, m.Fields["expires_in_seconds"].(float64) > 0)
+		case "node":
+			require.True(t, m.Fields["expires_in_seconds"].(float64) < 0)
+		}
+	}
+
+	acc.AssertContainsTaggedFields(t, "cockroachdb_certificate",
+		map[string]interface{}{
+			"status": "error",
+			"error":  "open certs/client.root.key: permission denied",
+		},
+		map[string]string{"node_id": "2", "type": "client", "server": u.Host})
+
+	// Node 3 could not be asked for its certificates
+	var errs []string
+	for _, err := range acc.Errors {
+		errs = append(errs, err.Error())
+	}
+	require.Contains(t, strings.Join(errs, "\n"), "/_status/certificates/3")
+	require.False(t, acc.HasPoint("cockroachdb_certificate",
+		map[string]string{"node_id": "3", "type": "ca", "server": u.Host}, "status", "ok"))
+}
+
+func TestCockroachdbClusterRollup(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/_status/nodes":
+			fmt.Fprintf(w, `{"nodes": [%s, %s]}`, response, responseV21)
+		case "/_status/nodes/1":
+			fmt.Fprint(w, response)
+		case "/_admin/v1/cluster":
+			fmt.Fprint(w, `{"clusterId": "0c5d3e2a-5f1b-4a8e-9d6f-2b7c1e4a9f30", "reportingEnabled": false, "enterpriseEnabled": true}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	Cockroachdb := NeCockroachdb()
+	Cockroachdb.Servers = []string{ts.URL + "/_status/nodes/1"}
+	Cockroachdb.Seeds = []string{ts.URL}
+	Cockroachdb.MetricInclude = []string{"none"}
+	Cockroachdb.ClusterRollup = true
+	Cockroachdb.ExpectedNodes = 3
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, Cockroachdb.Gather(acc))
+	require.NoError(t, acc.FirstError())
+
+	acc.AssertContainsTaggedFields(t, "cockroachdb_cluster",
+		map[string]interface{}{
+			"nodes":                  2,
+			"nodes_expected":         3,
+			"nodes_missing":          1,
+			"versions":               2,
+			"capacity":               int64(512962286915584),
+			"capacity_available":     int64(456353737746432),
+			"capacity_used":          int64(56939820),
+			"capacity_used_percent":  100 * float64(56939820) / float64(56939820+456353737746432),
+			"clock_offset_max_nanos": float64(250000000),
+			"clock_offset_max_ratio": float64(0.25),
+		},
+		map[string]string{"cluster_id": "0c5d3e2a-5f1b-4a8e-9d6f-2b7c1e4a9f30"})
+
+	// The prometheus source has no node statuses to compute it from
+	Cockroachdb = NeCockroachdb()
+	Cockroachdb.Servers = []string{ts.URL}
+	Cockroachdb.Source = "prometheus"
+	Cockroachdb.ClusterRollup = true
+	acc = &testutil.Accumulator{}
+	require.EqualError(t, Cockroachdb.Gather(acc), `cluster_rollup is not supported with source "prometheus"`)
+	require.False(t, acc.HasMeasurement("cockroachdb_cluster"))
+}
+
+func TestCockroachdbClusterIDFailure(t *testing.T) {
+	var lookups int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case clusterPath:
+			atomic.AddInt32(&lookups, 1)
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			fmt.Fprint(w, response)
+		}
+	}))
+	defer ts.Close()
+
+	Cockroachdb := NeCockroachdb()
+	Cockroachdb.Servers = []string{ts.URL + "/_status/nodes/1"}
+	Cockroachdb.MetricInclude = []string{"none"}
+	Cockroachdb.ClusterRollup = true
+
+	// The failed lookup is requested and reported once per gather, the
+	// rollup included
+	acc := &testutil.Accumulator{}
+	require.NoError(t, Cockroachdb.Gather(acc))
+	require.Equal(t, int32(1), atomic.LoadInt32(&lookups))
+	require.Len(t, acc.Errors, 1)
+	require.True(t, acc.HasMeasurement("cockroachdb_cluster"))
+	for _, m := range acc.Metrics {
+		require.NotContains(t, m.Tags, "cluster_id", m.Measurement)
+	}
+
+	// and is not requested again before clusterIDRetry
+	acc = &testutil.Accumulator{}
+	require.NoError(t, Cockroachdb.Gather(acc))
+	require.Equal(t, int32(1), atomic.LoadInt32(&lookups))
+	require.NoError(t, acc.FirstError())
+	require.True(t, acc.HasMeasurement("cockroachdb_cluster"))
+
+	Cockroachdb.clusterIDFailed = time.Now().Add(-clusterIDRetry)
+	acc = &testutil.Accumulator{}
+	require.NoError(t, Cockroachdb.Gather(acc))
+	require.Equal(t, int32(2), atomic.LoadInt32(&lookups))
+	require.Len(t, acc.Errors, 1)
+}
+
+func TestCockroachdbClusterName(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/_admin/v1/cluster":
+			fmt.Fprint(w, `{"clusterId": "6e0b9a2c-1f1d-4f4a-8c55-0e1e2f3a4b5c"}`)
+		case "/_admin/v1/liveness":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			fmt.Fprintln(w, response)
+		}
+	}))
+	defer ts.Close()
+
+	Cockroachdb := NeCockroachdb()
+	Cockroachdb.Servers = []string{ts.URL + "/_status/nodes/1"}
+	Cockroachdb.MetricInclude = []string{"sys.uptime"}
+	Cockroachdb.Liveness = true
+
+	// The top-level servers are tagged with the cluster ID
+	acc := &testutil.Accumulator{}
+	require.NoError(t, Cockroachdb.Gather(acc))
+	require.True(t, acc.HasMeasurement("cockroachdb"))
+	for _, m := range acc.Metrics {
+		require.Equal(t, "6e0b9a2c-1f1d-4f4a-8c55-0e1e2f3a4b5c", m.Tags["cluster_id"], m.Measurement)
+		require.NotContains(t, m.Tags, "cluster_name", m.Measurement)
+	}
+	require.Error(t, acc.FirstError())
+	require.False(t, strings.HasPrefix(acc.FirstError().Error(), "cluster "))
+
+	// and with its name when set
+	Cockroachdb.ClusterName = "staging"
+	acc = &testutil.Accumulator{}
+	require.NoError(t, Cockroachdb.Gather(acc))
+	require.True(t, acc.HasMeasurement("cockroachdb"))
+	for _, m := range acc.Metrics {
+		require.Equal(t, "6e0b9a2c-1f1d-4f4a-8c55-0e1e2f3a4b5c", m.Tags["cluster_id"], m.Measurement)
+		require.Equal(t, "staging", m.Tags["cluster_name"], m.Measurement)
+	}
+	require.Contains(t, acc.FirstError().Error(), "cluster staging: ")
+}
+
+func TestMaxOffset(t *testing.T) {
+	require.Equal(t, 500*time.Millisecond, maxOffset(nil))
+	require.Equal(t, 500*time.Millisecond, maxOffset([]string{"cockroach", "start", "--insecure"}))
+	require.Equal(t, time.Second, maxOffset([]string{"cockroach", "start", "--max-offset=1s"}))
+	require.Equal(t, 250*time.Millisecond, maxOffset([]string{"cockroach", "start", "--max-offset", "250ms"}))
+}
+
+func TestCockroachdbClusters(t *testing.T) {
+	staging := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/_admin/v1/cluster":
+			fmt.Fprint(w, `{"clusterId": "6e0b9a2c-1f1d-4f4a-8c55-0e1e2f3a4b5c"}`)
+		default:
+			fmt.Fprintln(w, response)
+		}
+	}))
+	defer staging.Close()
+
+	production := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/_admin/v1/login":
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "1", Path: "/"})
+			fmt.Fprintln(w, "{}")
+			return
+		}
+		if _, err := r.Cookie("session"); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/_admin/v1/cluster":
+			fmt.Fprint(w, `{"clusterId": "d1a4f6b8-7c3e-4b2a-9e0f-5a6b7c8d9e0f"}`)
+		case "/_status/nodes":
+			fmt.Fprintf(w, `{"nodes": [%s]}`, response)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	tlsConfig, err := pki.TLSServerConfig().TLSConfig()
+	require.NoError(t, err)
+	production.TLS = tlsConfig
+	production.StartTLS()
+	defer production.Close()
+
+	Cockroachdb := NeCockroachdb()
+	Cockroachdb.MetricInclude = []string{"sys.uptime"}
+	Cockroachdb.Cluster = []*Cluster{
+		{
+			Name:    "staging",
+			Servers: []string{staging.URL + "/_status/nodes/1"},
+		},
+		{
+			Name:         "production",
+			Seeds:        []string{production.URL},
+			Username:     "telegraf",
+			Password:     "secret",
+			ClientConfig: *pki.TLSClientConfig(),
+		},
+	}
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, Cockroachdb.Gather(acc))
+	require.NoError(t, acc.FirstError())
+
+	clusters := make(map[string]string)
+	for _, m := range acc.Metrics {
+		require.Contains(t, m.Tags, "cluster_name", m.Measurement)
+		require.Contains(t, m.Tags, "cluster_id", m.Measurement)
+		clusters[m.Tags["cluster_name"]] = m.Tags["cluster_id"]
+		if m.Measurement == "cockroachdb" {
+			require.Equal(t, "1", m.Tags["node_id"])
+			require.Equal(t, float64(18796), m.Fields["sys.uptime"])
+		}
+	}
+	require.Equal(t, map[string]string{
+		"staging":    "6e0b9a2c-1f1d-4f4a-8c55-0e1e2f3a4b5c",
+		"production": "d1a4f6b8-7c3e-4b2a-9e0f-5a6b7c8d9e0f",
+	}, clusters)
+
+	// The localhost default only applies without cluster tables
+	require.Empty(t, Cockroachdb.Servers)
+
+	// Errors are reported with the name of the cluster
+	production.Close()
+	acc = &testutil.Accumulator{}
+	require.NoError(t, Cockroachdb.Gather(acc))
+	require.Error(t, acc.FirstError())
+	require.Contains(t, acc.FirstError().Error(), "cluster production: ")
+	require.True(t, acc.HasPoint("cockroachdb",
+		map[string]string{"cluster_name": "staging", "cluster_id": "6e0b9a2c-1f1d-4f4a-8c55-0e1e2f3a4b5c",
+			"server": strings.TrimPrefix(staging.URL, "http://"), "addressField": "roach1:26257",
+			"node_id": "1", "version": "v2.0.3"},
+		"sys.uptime", float64(18796)))
+}
+
+func TestCockroachdbBackfill(t *testing.T) {
+	var queried tsQueryRequest
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ts/query":
+			require.Equal(t, "POST", r.Method)
+			atomic.AddInt32(&requests, 1)
+			queried = tsQueryRequest{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&queried))
+
+			start, err := strconv.ParseInt(queried.StartNanos, 10, 64)
+			require.NoError(t, err)
+			var results []string
+			for _, q := range queried.Queries {
+				results = append(results, fmt.Sprintf(
+					`{"query": {"name": %q, "sources": %q}, "datapoints": [{"timestampNanos": "%d", "value": 100.4}, {"timestampNanos": "%d", "value": 110.6}]}`,
+					q.Name, q.Sources, start, start+int64(10*time.Second)))
+			}
+			fmt.Fprintf(w, `{"results": [%s]}`, strings.Join(results, ","))
+		default:
+			fmt.Fprintln(w, response)
+		}
+	}))
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "cockroachdb")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	state := filepath.Join(dir, "cockroachdb.state")
+
+	Cockroachdb := NeCockroachdb()
+	Cockroachdb.Servers = []string{ts.URL + "/_status/nodes/1"}
+	Cockroachdb.MetricInclude = []string{"sys.uptime", "sys.cpu.user.percent", "capacity"}
+	Cockroachdb.Backfill = true
+	Cockroachdb.BackfillStateFile = state
+
+	// Nothing to backfill on the first gather
+	acc := &testutil.Accumulator{}
+	require.NoError(t, Cockroachdb.Gather(acc))
+	require.NoError(t, acc.FirstError())
+	require.Nil(t, queried.Queries)
+	last, err := readBackfillState(state)
+	require.NoError(t, err)
+	require.False(t, last.IsZero())
+
+	// A restart 10 minutes later backfills the gap, up to backfill_max_age
+	gap := time.Now().Add(-10 * time.Minute)
+	require.NoError(t, writeBackfillState(state, gap))
+
+	Cockroachdb = NeCockroachdb()
+	Cockroachdb.Servers = []string{ts.URL + "/_status/nodes/1"}
+	Cockroachdb.MetricInclude = []string{"sys.uptime", "sys.cpu.user.percent", "capacity"}
+	Cockroachdb.Backfill = true
+	Cockroachdb.BackfillStateFile = state
+	Cockroachdb.BackfillMaxAge.Duration = 5 * time.Minute
+
+	acc = &testutil.Accumulator{}
+	require.NoError(t, Cockroachdb.Gather(acc))
+	require.NoError(t, acc.FirstError())
+
+	// The node and its store are queried separately
+	require.Equal(t, int32(2), atomic.LoadInt32(&requests))
+	require.Equal(t, "10000000000", queried.SampleNanos)
+	start, err := strconv.ParseInt(queried.StartNanos, 10, 64)
+	require.NoError(t, err)
+	require.True(t, start > gap.Add(4*time.Minute).UnixNano())
+
+	first := time.Unix(0, start)
+	var backfilled int
+	for _, m := range acc.Metrics {
+		if !m.Time.Equal(first) {
+			continue
+		}
+		backfilled++
+		require.Equal(t, "1", m.Tags["node_id"])
+		switch m.Measurement {
+		case "cockroachdb":
+			require.Equal(t, map[string]interface{}{
+				"sys.uptime":           float64(100.4),
+				"sys.cpu.user.percent": float64(100.4),
+			}, m.Fields)
+		case "cockroachdb_store":
+			require.Equal(t, "1", m.Tags["store_id"])
+			require.Equal(t, map[string]interface{}{"capacity": int64(100)}, m.Fields)
+		}
+	}
+	require.Equal(t, 2, backfilled)
+	require.True(t, acc.HasPoint("cockroachdb",
+		map[string]string{"server": strings.TrimPrefix(ts.URL, "http://"), "addressField": "roach1:26257", "node_id": "1", "version": "v2.0.3"},
+		"sys.uptime", float64(110.6)))
+
+	// The state moved to this gather, no gap is left
+	last, err = readBackfillState(state)
+	require.NoError(t, err)
+	require.True(t, last.After(gap.Add(9*time.Minute)))
+	queried = tsQueryRequest{}
+	acc = &testutil.Accumulator{}
+	require.NoError(t, Cockroachdb.Gather(acc))
+	require.Nil(t, queried.Queries)
+}
+
+func TestCockroachdbBackfillRetry(t *testing.T) {
+	var requests, largest int32
+	var failing int32 = 1
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ts/query":
+			atomic.AddInt32(&requests, 1)
+			if atomic.LoadInt32(&failing) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			var queried tsQueryRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&queried))
+			if n := int32(len(queried.Queries)); n > atomic.LoadInt32(&largest) {
+				atomic.StoreInt32(&largest, n)
+			}
+			start, err := strconv.ParseInt(queried.StartNanos, 10, 64)
+			require.NoError(t, err)
+			var results []string
+			for _, q := range queried.Queries {
+				results = append(results, fmt.Sprintf(
+					`{"query": {"name": %q}, "datapoints": [{"timestampNanos": "%d", "value": 1}]}`, q.Name, start))
+			}
+			fmt.Fprintf(w, `{"results": [%s]}`, strings.Join(results, ","))
+		default:
+			fmt.Fprintln(w, response)
+		}
+	}))
+	defer ts.Close()
+
+	Cockroachdb := NeCockroachdb()
+	Cockroachdb.Servers = []string{ts.URL + "/_status/nodes/1"}
+	Cockroachdb.Backfill = true
+	gap := time.Now().Add(-10 * time.Minute)
+	Cockroachdb.lastGather = gap
+
+	// A failed backfill keeps the window for the next gather
+	acc := &testutil.Accumulator{}
+	require.NoError(t, Cockroachdb.Gather(acc))
+	require.Error(t, acc.FirstError())
+	require.Equal(t, gap, Cockroachdb.lastGather)
+	for _, m := range acc.Metrics {
+		require.True(t, m.Time.After(gap.Add(9*time.Minute)), m.Measurement)
+	}
+
+	atomic.StoreInt32(&failing, 0)
+	atomic.StoreInt32(&requests, 0)
+	acc = &testutil.Accumulator{}
+	require.NoError(t, Cockroachdb.Gather(acc))
+	require.NoError(t, acc.FirstError())
+	require.True(t, Cockroachdb.lastGather.After(gap))
+
+	// Every metric of the node is backfilled, in batches
+	require.True(t, atomic.LoadInt32(&requests) > 2)
+	require.Equal(t, int32(backfillBatchSize), atomic.LoadInt32(&largest))
+	backfilled := false
+	for _, m := range acc.Metrics {
+		if m.Measurement == "cockroachdb" && m.Time.Before(gap.Add(time.Minute)) {
+			backfilled = true
+			require.Equal(t, float64(1), m.Fields["sys.uptime"])
+		}
+	}
+	require.True(t, backfilled)
+}
+
+func TestCockroachdbBackfillResolution(t *testing.T) {
+	Cockroachdb := NeCockroachdb()
+	Cockroachdb.Servers = []string{"http://localhost:0/_status/nodes/1"}
+	Cockroachdb.Backfill = true
+	Cockroachdb.BackfillResolution.Duration = 15 * time.Second
+
+	acc := &testutil.Accumulator{}
+	err := Cockroachdb.Gather(acc)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "backfill_resolution")
+
+	Cockroachdb.BackfillResolution.Duration = 30 * time.Second
+	require.NoError(t, Cockroachdb.checkBackfill())
+}
+
+func TestCockroachdbSubsystemNaming(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, response)
+	}))
+	defer ts.Close()
+
+	Cockroachdb := NeCockroachdb()
+	Cockroachdb.Servers = []string{ts.URL}
+	Cockroachdb.MeasurementNaming = "subsystem"
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, Cockroachdb.Gather(acc))
+	require.NoError(t, acc.FirstError())
+
+	require.True(t, acc.HasFloatField("cockroachdb_sys", "uptime"))
+	require.True(t, acc.HasField("cockroachdb_sys", "cpu.user.percent"))
+	require.True(t, acc.HasField("cockroachdb_exec", "latency-max"))
+	require.True(t, acc.HasField("cockroachdb_clock_offset", "meannanos"))
+	require.True(t, acc.HasField("cockroachdb_store_capacity", "available"))
+	require.True(t, acc.HasField("cockroachdb_store", "capacity"))
+	require.False(t, acc.HasField("cockroachdb", "sys.uptime"))
+	require.Equal(t, "1", acc.TagValue("cockroachdb_sys", "node_id"))
+	require.Equal(t, "1", acc.TagValue("cockroachdb_store_capacity", "store_id"))
+
+	// Underscore field keys
+	Cockroachdb.FieldNaming = "underscore"
+	acc = &testutil.Accumulator{}
+	require.NoError(t, Cockroachdb.Gather(acc))
+	require.True(t, acc.HasField("cockroachdb_sys", "cpu_user_percent"))
+	require.True(t, acc.HasField("cockroachdb_exec", "latency_max"))
+
+	// Flat measurements with underscore field keys
+	Cockroachdb.MeasurementNaming = ""
+	acc = &testutil.Accumulator{}
+	require.NoError(t, Cockroachdb.Gather(acc))
+	require.True(t, acc.HasFloatField("cockroachdb", "sys_uptime"))
+	require.True(t, acc.HasField("cockroachdb", "exec_latency_max"))
+	require.True(t, acc.HasField("cockroachdb_store", "capacity_available"))
+	require.False(t, acc.HasMeasurement("cockroachdb_sys"))
+
+	Cockroachdb.MeasurementNaming = "nested"
+	require.EqualError(t, Cockroachdb.Gather(&testutil.Accumulator{}), `unknown measurement_naming "nested"`)
+}
+
+func TestFieldName(t *testing.T) {
+	tests := []struct {
+		naming string
+		key    string
+		want   string
+	}{
+		{"", "sql.distsql.exec.latency-p99.9", "sql.distsql.exec.latency-p99.9"},
+		{"underscore", "sql.distsql.exec.latency-p99.9", "sql_distsql_exec_latency_p99_9"},
+		{"underscore", "rocksdb.numSSTables", "rocksdb_numSSTables"},
+		{"snake_case", "rocksdb.numSSTables", "rocksdb_num_ss_tables"},
+		{"snake_case", "clock-offset.meannanos", "clock_offset_meannanos"},
+	}
+	for _, tt := range tests {
+		c := &Cockroachdb{FieldNaming: tt.naming}
+		require.Equal(t, tt.want, c.fieldName(tt.key), tt.key)
+	}
+}
+
+// TestBuiltinQueriesColumnTypes checks that the aggregates of the built-in
+// queries are cast, as sum() over INT returns DECIMAL which the driver scans
+// as a string.
+func TestBuiltinQueriesColumnTypes(t *testing.T) {
+	for _, q := range builtinQueries {
+		query := q.Sqlquery
+		for {
+			i := strings.Index(query, "sum(")
+			if i < 0 {
+				break
+			}
+			query = query[i+len("sum("):]
+
+			depth := 1
+			end := 0
+			for end < len(query) && depth > 0 {
+				switch query[end] {
+				case '(':
+					depth++
+				case ')':
+					depth--
+				}
+				end++
+			}
+			rest := query[end:]
+			require.True(t, strings.HasPrefix(rest, "::INT8") || strings.HasPrefix(rest, "::FLOAT8"),
+				"%s: sum(%s is not cast", q.Measurement, query[:end])
+		}
+	}
+}
+
+func TestScanRow(t *testing.T) {
+	columns := []string{"node_id", "application_name", "count", "service_lat_avg", "error"}
+	row := fakeRow{int64(2), []byte("$ cockroach sql"), int64(42), float64(0.0015), nil}
+
+	tags, fields, err := scanRow(row, columns, []string{"node_id", " application_name"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		"node_id":          "2",
+		"application_name": "$ cockroach sql",
+	}, tags)
+	require.Equal(t, map[string]interface{}{
+		"count":           int64(42),
+		"service_lat_avg": float64(0.0015),
+	}, fields)
+}
+
+func TestCockroachdbUnknownSource(t *testing.T) {
+	Cockroachdb := NeCockroachdb()
+	Cockroachdb.Source = "carrier-pigeon"
+
+	acc := &testutil.Accumulator{}
+	require.Error(t, Cockroachdb.Gather(acc))
+}
+
+var livenessResponseJSON = `
+{
+  "livenesses": [
+    {
+      "nodeId": 1,
+      "epoch": "3",
+      "expiration": {"wallTime": "1530594698327860200", "logical": 0},
+      "draining": false,
+      "decommissioning": false
+    },
+    {
+      "nodeId": 2,
+      "epoch": 7,
+      "expiration": {"wallTime": 1530594680000000000, "logical": 0},
+      "draining": false,
+      "decommissioning": false
+    },
+    {
+      "nodeId": 3,
+      "epoch": "1",
+      "expiration": {"wallTime": "1530594600000000000", "logical": 0},
+      "draining": true,
+      "decommissioning": true
+    }
+  ],
+  "statuses": {
+    "1": "LIVE",
+    "2": "UNAVAILABLE",
+    "3": "DECOMMISSIONING"
+  }
+}
+`
+
+var responseV21 = `
+{
+  "desc": {
+    "nodeId": 2,
+    "address": {"networkField": "tcp", "addressField": "roach2:26257"},
+    "attrs": {"attrs": []},
+    "locality": {"tiers": [{"key": "region", "value": "us-east1"}]},
+    "ServerVersion": {"majorVal": 2, "minorVal": 1, "patch": 0, "unstable": 0},
+    "buildTag": "v2.1.6",
+    "startedAt": "1554000000000000000"
+  },
+  "buildInfo": {"goVersion": "go1.10.7", "tag": "v2.1.6", "distribution": "CCL", "type": "release"},
+  "startedAt": "1554000000000000000",
+  "updatedAt": "1554000600000000000",
+  "metrics": {
+    "sql.txn.latency-p99": 25165823,
+    "sql.distsql.queries.spilled": 3,
+    "clock-offset.meannanos": -250000000,
+    "sys.gc.pause.percent": 0.00012,
+    "sys.fd.softlimit": 18446744073709551615,
+    "sys.uptime": 600
+  },
+  "storeStatuses": [
+    {
+      "desc": {
+        "storeId": 2,
+        "attrs": {"attrs": ["ssd"]},
+        "node": {"nodeId": 2},
+        "capacity": {
+          "capacity": "1000000000000",
+          "available": "900000000000",
+          "used": "1048576",
+          "logicalBytes": "524288",
+          "rangeCount": 25,
+          "leaseCount": 9,
+          "queriesPerSecond": 3.5,
+          "writesPerSecond": 1.25,
+          "bytesPerReplica": {"p10": 0, "p25": 0, "p50": 120, "p75": 4096, "p90": 65536, "pMax": 1048576},
+          "writesPerReplica": {"p10": 0, "p25": 0, "p50": 0, "p75": 0.01, "p90": 0.5, "pMax": 1.2}
+        }
+      },
+      "metrics": {
+        "capacity": 1000000000000,
+        "ranges.overreplicated": 1,
+        "ranges.unavailable": 0
+      }
+    }
+  ],
+  "args": ["cockroach", "start", "--max-offset=1s"],
+  "env": [],
+  "latencies": {"1": "1100000"},
+  "activity": {"1": {"incoming": "2048", "outgoing": "4096", "latency": "1100000"}},
+  "totalSystemMemory": "16777216000",
+  "numCpus": 4
+}
+`
+
+var responseV192 = `
+{
+  "desc": {
+    "nodeId": 3,
+    "address": {"networkField": "tcp", "addressField": "roach3:26257"},
+    "attrs": {"attrs": []},
+    "locality": {"tiers": [{"key": "region", "value": "eu-west1"}, {"key": "zone", "value": "eu-west1-c"}]},
+    "ServerVersion": {"majorVal": 19, "minorVal": 2, "patch": 0, "unstable": 0},
+    "buildTag": "v19.2.4",
+    "startedAt": "1580000000000000000",
+    "localityAddress": [],
+    "clusterName": ""
+  },
+  "buildInfo": {"goVersion": "go1.12.12", "tag": "v19.2.4", "distribution": "CCL", "type": "release", "channel": "official-binary"},
+  "startedAt": "1580000000000000000",
+  "updatedAt": "1580000900000000000",
+  "metrics": {
+    "sql.conns": 12,
+    "sql.distsql.flows.queued": 0,
+    "sys.cpu.user.percent": 0.25,
+    "txn.restarts.txnaborted": 4
+  },
+  "storeStatuses": [
+    {
+      "desc": {
+        "storeId": 3,
+        "attrs": {"attrs": []},
+        "node": {"nodeId": 3},
+        "capacity": {
+          "capacity": "1000000000000",
+          "available": "750000000000",
+          "used": "250000000000",
+          "logicalBytes": "125000000000",
+          "rangeCount": 1200,
+          "leaseCount": 400,
+          "queriesPerSecond": 250.5,
+          "writesPerSecond": 80.25,
+          "bytesPerReplica": {"p10": 1024, "p25": 65536, "p50": 33554432, "p75": 67108864, "p90": 134217728, "pMax": 536870912},
+          "writesPerReplica": {"p10": 0, "p25": 0, "p50": 0.05, "p75": 0.5, "p90": 2, "pMax": 40}
+        }
+      },
+      "metrics": {
+        "capacity.available": 750000000000,
+        "rocksdb.read-amplification": 4,
+        "requests.slow.latch": 0
+      }
+    }
+  ],
+  "latencies": {},
+  "activity": {}
+}
+`
+
+var responseV202 = `
+{
+  "desc": {
+    "nodeId": 4,
+    "address": {"networkField": "tcp", "addressField": "roach4:26257"},
+    "attrs": {"attrs": []},
+    "locality": {"tiers": []},
+    "ServerVersion": {"majorVal": 20, "minorVal": 2, "patch": 0, "internal": 0},
+    "buildTag": "v20.2.7",
+    "startedAt": "1617000000000000000",
+    "sqlAddress": {"networkField": "tcp", "addressField": "roach4:26257"},
+    "httpAddress": {"networkField": "tcp", "addressField": "roach4:8080"}
+  },
+  "buildInfo": {"goVersion": "go1.13.14", "tag": "v20.2.7", "distribution": "CCL", "type": "release", "channel": "official-binary", "envChannel": "", "enabledAssertions": false},
+  "startedAt": "1617000000000000000",
+  "updatedAt": "1617000060000000000",
+  "metrics": {
+    "sys.cpu.combined.percent-normalized": 0.0625,
+    "sql.statements.active": 2,
+    "sys.host.disk.read.bytes": 9876543210
+  },
+  "storeStatuses": [
+    {
+      "desc": {
+        "storeId": 4,
+        "attrs": {"attrs": []},
+        "node": {"nodeId": 4},
+        "capacity": {
+          "capacity": "500000000000",
+          "available": "400000000000",
+          "used": "100000000000",
+          "logicalBytes": "50000000000",
+          "rangeCount": 600,
+          "leaseCount": 200,
+          "queriesPerSecond": 12.5,
+          "writesPerSecond": 4,
+          "bytesPerReplica": {"p10": 0, "p25": 0, "p50": 0, "p75": 0, "p90": 0, "pMax": 0},
+          "writesPerReplica": {"p10": 0, "p25": 0, "p50": 0, "p75": 0, "p90": 0, "pMax": 0}
+        }
+      },
+      "metrics": {
+        "rocksdb.estimated-pending-compaction": 0,
+        "rebalancing.queriespersecond": 12.5
+      }
+    }
+  ],
+  "latencies": {},
+  "activity": {}
+}
+`
+
+var responseV212 = `
+{
+  "desc": {
+    "nodeId": 5,
+    "address": {"networkField": "tcp", "addressField": "roach5:26257"},
+    "attrs": {"attrs": []},
+    "locality": {"tiers": []},
+    "ServerVersion": {"majorVal": 21, "minorVal": 2, "patch": 0, "internal": 0},
+    "buildTag": "v21.2.3",
+    "startedAt": "1640000000000000000",
+    "sqlAddress": {"networkField": "tcp", "addressField": "roach5:26257"},
+    "httpAddress": {"networkField": "tcp", "addressField": "roach5:8080"}
+  },
+  "buildInfo": {"goVersion": "go1.16.6", "tag": "v21.2.3", "distribution": "CCL", "type": "release", "channel": "official-binary"},
+  "startedAt": "1640000000000000000",
+  "updatedAt": "1640000030000000000",
+  "metrics": {
+    "admission.admitted.kv": 12345,
+    "jobs.changefeed.currently_running": 1,
+    "sql.txn.contended.count": 8,
+    "sys.host.net.recv.bytes": 123456789
+  },
+  "storeStatuses": [
+    {
+      "desc": {
+        "storeId": 5,
+        "attrs": {"attrs": []},
+        "node": {"nodeId": 5},
+        "capacity": {
+          "capacity": "104857600",
+          "available": "52428800",
+          "used": "52428800",
+          "logicalBytes": "26214400",
+          "rangeCount": 45,
+          "leaseCount": 15,
+          "queriesPerSecond": 0.5,
+          "writesPerSecond": 0.25,
+          "l0Sublevels": "0",
+          "bytesPerReplica": {"p10": 0, "p25": 0, "p50": 0, "p75": 0, "p90": 0, "pMax": 0},
+          "writesPerReplica": {"p10": 0, "p25": 0, "p50": 0, "p75": 0, "p90": 0, "pMax": 0}
+        }
+      },
+      "metrics": {
+        "storage.l0-sublevels": 0,
+        "ranges.unavailable": 0
+      }
+    }
+  ],
+  "latencies": {},
+  "activity": {}
+}
+`
+
+var problemRangesResponseJSON = `
+{
+  "nodeId": 1,
+  "problemsByNodeId": {
+    "1": {
+      "errorMessage": "",
+      "unavailableRangeIds": ["42"],
+      "raftLeaderNotLeaseHolderRangeIds": [],
+      "noRaftLeaderRangeIds": [],
+      "noLeaseRangeIds": ["42"],
+      "underreplicatedRangeIds": ["7", "8"],
+      "overreplicatedRangeIds": [],
+      "quiescentEqualsTickingRangeIds": [],
+      "raftLogTooLargeRangeIds": []
+    },
+    "2": {
+      "errorMessage": "rpc error: node unavailable"
+    }
+  }
+}
+`
+
+var statementsResponseJSON = `
+{
+  "statements": [
+    {
+      "key": {
+        "keyData": {"query": "SELECT * FROM users WHERE id = $1", "app": "shop", "database": "shop", "distSQL": false, "failed": false, "implicitTxn": true},
+        "nodeId": 1
+      },
+      "stats": {
+        "count": "100", "firstAttemptCount": "98", "maxRetries": "1",
+        "numRows": {"mean": 1, "squaredDiffs": 0},
+        "serviceLat": {"mean": 0.002, "squaredDiffs": 0.0001},
+        "latencyInfo": {"min": 0.001, "max": 0.05}
+      }
+    },
+    {
+      "key": {
+        "keyData": {"query": "SELECT * FROM users WHERE id = $1", "app": "shop", "database": "shop", "distSQL": true, "failed": false, "implicitTxn": true},
+        "nodeId": 2
+      },
+      "stats": {
+        "count": "50", "firstAttemptCount": "50", "maxRetries": "0",
+        "numRows": {"mean": 1, "squaredDiffs": 0},
+        "serviceLat": {"mean": 0.004, "squaredDiffs": 0.0002},
+        "latencyInfo": {"min": 0.001, "max": 0.08}
+      }
+    },
+    {
+      "key": {
+        "keyData": {"query": "SELECT * FROM users WHERE id = $1", "app": "shop", "database": "shop", "distSQL": false, "failed": true, "implicitTxn": true},
+        "nodeId": 1
+      },
+      "stats": {
+        "count": "5", "firstAttemptCount": "5", "maxRetries": "0",
+        "numRows": {"mean": 0, "squaredDiffs": 0},
+        "serviceLat": {"mean": 0.01, "squaredDiffs": 0}
+      }
+    },
+    {
+      "key": {
+        "keyData": {"query": "INSERT INTO orders VALUES ($1, $2)", "app": "shop", "database": "shop", "failed": false, "implicitTxn": true},
+        "nodeId": 1
+      },
+      "stats": {
+        "count": "10", "firstAttemptCount": "10", "maxRetries": "0",
+        "numRows": {"mean": 1, "squaredDiffs": 0},
+        "serviceLat": {"mean": 0.02, "squaredDiffs": 0}
+      }
+    },
+    {
+      "key": {
+        "keyData": {"query": "UPDATE stock SET n = n - _ WHERE id = $1", "app": "shop", "database": "shop", "failed": false, "implicitTxn": true},
+        "nodeId": 2
+      },
+      "stats": {
+        "count": "1", "firstAttemptCount": "1", "maxRetries": "0",
+        "numRows": {"mean": 1, "squaredDiffs": 0},
+        "serviceLat": {"mean": 0.001, "squaredDiffs": 0}
+      }
+    },
+    {
+      "key": {
+        "keyData": {"query": "SELECT * FROM system.jobs", "app": "$ internal-get-jobs", "database": "system", "failed": false, "implicitTxn": true},
+        "nodeId": 1
+      },
+      "stats": {
+        "count": "100000", "firstAttemptCount": "100000", "maxRetries": "0",
+        "numRows": {"mean": 10, "squaredDiffs": 0},
+        "serviceLat": {"mean": 1, "squaredDiffs": 0}
+      }
+    }
+  ],
+  "lastReset": "2018-09-03T10:00:00Z",
+  "internalAppNamePrefix": "$ internal"
+}
+`
+
+var sessionsResponseJSON = `
+{
+  "sessions": [
+    {
+      "nodeId": 1,
+      "username": "shop",
+      "clientAddress": "10.0.0.5:54321",
+      "applicationName": "shop",
+      "activeQueries": [
+        {"id": "15f3c9a8e3b5a0c80000000000000001", "start": "%s", "sql": "SELECT * FROM orders FOR UPDATE", "isDistributed": true, "phase": "EXECUTING"}
+      ],
+      "start": "%s",
+      "activeTxn": {"id": "a0b1c2d3-0000-0000-0000-000000000001", "start": "%s"}
+    },
+    {
+      "nodeId": 1,
+      "username": "shop",
+      "clientAddress": "10.0.0.6:54321",
+      "applicationName": "shop",
+      "activeQueries": [
+        {"id": "15f3c9a8e3b5a0c80000000000000002", "start": "%s", "sql": "SELECT 1", "isDistributed": false, "phase": "PREPARING"}
+      ],
+      "start": "%s"
+    },
+    {
+      "nodeId": 2,
+      "username": "root",
+      "clientAddress": "10.0.0.7:54321",
+      "applicationName": "",
+      "activeQueries": [],
+      "start": "%s"
+    }
+  ],
+  "errors": [
+    {"nodeId": 3, "message": "node unavailable"}
+  ]
+}
+`
+
+var hotRangesResponseJSON = `
+{
+  "nodeId": 1,
+  "hotRangesByNodeId": {
+    "1": {
+      "errorMessage": "",
+      "stores": [
+        {
+          "storeId": 1,
+          "hotRanges": [
+            {"desc": {"rangeId": "7", "startKey": "Eg==", "endKey": "Ew=="}, "queriesPerSecond": 0.5},
+            {"desc": {"rangeId": "84", "startKey": "vIk=", "endKey": "vIo="}, "queriesPerSecond": 950.5},
+            {"desc": {"rangeId": "42", "startKey": "u4k=", "endKey": "u4o="}, "queriesPerSecond": 120}
+          ]
+        }
+      ]
+    },
+    "2": {
+      "stores": [
+        {
+          "storeId": 2,
+          "hotRanges": [
+            {"desc": {"rangeId": "97"}, "queriesPerSecond": 300, "databaseName": "shop", "tableName": "orders", "indexName": "orders_pkey"}
+          ]
+        }
+      ]
+    },
+    "3": {
+      "errorMessage": "rpc error: node unavailable"
+    }
+  }
+}
+`
+
+var tableStatsResponseJSON = `
+{
+  "rangeCount": "3",
+  "replicaCount": "9",
+  "nodeCount": "3",
+  "stats": {
+    "liveBytes": "524288",
+    "keyBytes": "131072",
+    "valBytes": "917504"
+  },
+  "approximateDiskBytes": "1048576",
+  "missingNodes": [
+    {"nodeId": "4", "errorMessage": "node unavailable"}
+  ],
+  "nodeIds": [1, 2, 3]
+}
+`
+
+var certificatesResponseJSON = `
+{
+  "certificates": [
+    {
+      "type": "CA",
+      "errorMessage": "",
+      "data": "",
+      "fields": [
+        {"issuer": "O=Cockroach,CN=Cockroach CA", "validFrom": "1514764800000000000", "validUntil": "1924992000000000000"},
+        {"issuer": "O=Cockroach,CN=Cockroach CA", "validFrom": "1514764800000000000", "validUntil": "1893456000000000000"}
+      ]
+    },
+    {
+      "type": "NODE",
+      "errorMessage": "",
+      "data": "",
+      "fields": [
+        {"issuer": "O=Cockroach,CN=Cockroach CA", "subject": "O=Cockroach,CN=node", "validFrom": "1514764800000000000", "validUntil": "1546300800000000000"}
+      ]
+    }
+  ]
+}
+`
+
+var jobsResponseJSON = `
+{
+  "jobs": [
+    {
+      "id": "364829124012032001",
+      "type": "BACKUP",
+      "description": "BACKUP DATABASE bank TO 'nodelocal:///backup/1'",
+      "username": "root",
+      "status": "succeeded",
+      "created": "2018-07-02T23:59:59Z",
+      "started": "2018-07-03T00:00:00Z",
+      "finished": "2018-07-03T00:30:00Z",
+      "modified": "2018-07-03T00:30:00Z",
+      "fractionCompleted": 1,
+      "error": ""
+    },
+    {
+      "id": "364829124012032004",
+      "type": "BACKUP",
+      "description": "BACKUP DATABASE bank TO 'nodelocal:///backup/2'",
+      "username": "root",
+      "status": "succeeded",
+      "created": "2018-07-04T00:00:00Z",
+      "started": "2018-07-04T00:00:00Z",
+      "finished": "2018-07-04T00:10:00Z",
+      "modified": "2018-07-04T00:10:00Z",
+      "fractionCompleted": 1,
+      "error": ""
+    },
+    {
+      "id": "364829124012032002",
+      "type": "RESTORE",
+      "description": "RESTORE DATABASE bank FROM 'nodelocal:///missing'",
+      "username": "root",
+      "status": "failed",
+      "created": "2018-07-03T01:00:00Z",
+      "started": "2018-07-03T01:00:00Z",
+      "finished": "2018-07-03T01:00:05Z",
+      "modified": "2018-07-03T01:00:05Z",
+      "fractionCompleted": 0,
+      "error": "file does not exist"
+    },
+    {
+      "id": "364829124012032003",
+      "type": "CHANGEFEED",
+      "description": "CREATE CHANGEFEED FOR TABLE bank.accounts INTO 'kafka://kafka:9092'",
+      "username": "root",
+      "status": "running",
+      "created": "2018-07-03T02:00:00Z",
+      "started": "2018-07-03T02:00:00Z",
+      "finished": null,
+      "modified": "2018-07-03T02:10:00Z",
+      "fractionCompleted": 0,
+      "error": "",
+      "highwaterTimestamp": "2018-07-03T02:09:58Z"
+    }
+  ]
+}
+`
+
+var varsResponse = `# HELP node_id node ID with labels for advertised RPC and HTTP addresses
+# TYPE node_id gauge
+node_id{advertise_addr="roach1:26257",http_addr="roach1:8080"} 1
+# HELP sql_conns Number of active sql connections
+# TYPE sql_conns gauge
+sql_conns 3
+# HELP sql_txn_abort_count Number of SQL transaction ABORT statements
+# TYPE sql_txn_abort_count counter
+sql_txn_abort_count 7
+# HELP capacity_available Available storage capacity
+# TYPE capacity_available gauge
+capacity_available{store="1"} 4.55453737746432e+14
+`
+
 var response = `
 {
   "desc": {
@@ -0,0 +1,68 @@
+package cockroachdb
+
+import (
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+// eventsPath lists the most recent entries of the cluster event log, newest
+// first: node joins and restarts, schema, zone config and setting changes.
+const eventsPath = "/_admin/v1/events"
+
+type eventsResponse struct {
+	Events []struct {
+		Timestamp   time.Time `json:"timestamp"`
+		EventType   string    `json:"eventType"`
+		TargetID    jsonInt64 `json:"targetId"`
+		ReportingID jsonInt64 `json:"reportingId"`
+		Info        string    `json:"info"`
+		UniqueID    string    `json:"uniqueId"`
+	} `json:"events"`
+}
+
+// gatherEvents adds a cockroachdb_event metric, timestamped with the event,
+// for every event logged since the previous gather. The first gather reports
+// the events still held by the event log.
+func (c *Cockroachdb) gatherEvents(acc telegraf.Accumulator) error {
+	var resp eventsResponse
+	server, err := c.getAdminJSON(eventsPath, &resp)
+	if err != nil {
+		return err
+	}
+
+	last := c.lastEvent
+	seen := c.lastEventIDs
+	if seen == nil {
+		seen = make(map[string]bool)
+	}
+	for _, ev := range resp.Events {
+		if ev.Timestamp.Before(c.lastEvent) {
+			continue
+		}
+		// Events sharing the last timestamp may have been reported already
+		if ev.Timestamp.Equal(c.lastEvent) && c.lastEventIDs[ev.UniqueID] {
+			continue
+		}
+
+		if ev.Timestamp.After(last) {
+			last = ev.Timestamp
+			seen = make(map[string]bool)
+		}
+		if ev.Timestamp.Equal(last) {
+			seen[ev.UniqueID] = true
+		}
+
+		tags := map[string]string{
+			"event_type":   ev.EventType,
+			"target_id":    formatInt64(ev.TargetID),
+			"reporting_id": formatInt64(ev.ReportingID),
+			"server":       server,
+		}
+		acc.AddFields("cockroachdb_event", map[string]interface{}{"info": ev.Info}, tags, ev.Timestamp)
+	}
+
+	c.lastEvent = last
+	c.lastEventIDs = seen
+	return nil
+}
@@ -0,0 +1,80 @@
+package cockroachdb
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/influxdata/telegraf"
+)
+
+// hotRangesPath lists the ranges of every store with the queries per second
+// they serve.
+const hotRangesPath = "/_status/hotranges"
+
+type hotRangesResponse struct {
+	HotRangesByNodeID map[string]struct {
+		ErrorMessage string `json:"errorMessage"`
+		Stores       []struct {
+			StoreID   int `json:"storeId"`
+			HotRanges []struct {
+				Desc struct {
+					RangeID jsonInt64 `json:"rangeId"`
+				} `json:"desc"`
+				QueriesPerSecond float64 `json:"queriesPerSecond"`
+				// Only reported by later CockroachDB versions
+				DatabaseName string `json:"databaseName"`
+				TableName    string `json:"tableName"`
+				IndexName    string `json:"indexName"`
+			} `json:"hotRanges"`
+		} `json:"stores"`
+	} `json:"hotRangesByNodeId"`
+}
+
+// gatherHotRanges adds a cockroachdb_hot_ranges point for the
+// HotRangesLimit ranges serving the most queries per second on each store.
+func (c *Cockroachdb) gatherHotRanges(acc telegraf.Accumulator) error {
+	var resp hotRangesResponse
+	server, err := c.getAdminJSON(hotRangesPath, &resp)
+	if err != nil {
+		return err
+	}
+
+	for nodeID, node := range resp.HotRangesByNodeID {
+		if node.ErrorMessage != "" {
+			acc.AddError(fmt.Errorf("hot ranges of node %s: %s", nodeID, node.ErrorMessage))
+			continue
+		}
+
+		for _, store := range node.Stores {
+			ranges := store.HotRanges
+			sort.SliceStable(ranges, func(i, j int) bool {
+				return ranges[i].QueriesPerSecond > ranges[j].QueriesPerSecond
+			})
+			if c.HotRangesLimit > 0 && len(ranges) > c.HotRangesLimit {
+				ranges = ranges[:c.HotRangesLimit]
+			}
+
+			for _, r := range ranges {
+				tags := map[string]string{
+					"range_id": formatInt64(r.Desc.RangeID),
+					"store_id": strconv.Itoa(store.StoreID),
+					"node_id":  nodeID,
+					"server":   server,
+				}
+				if r.DatabaseName != "" {
+					tags["database"] = r.DatabaseName
+				}
+				if r.TableName != "" {
+					tags["table"] = r.TableName
+				}
+				if r.IndexName != "" {
+					tags["index"] = r.IndexName
+				}
+				acc.AddFields("cockroachdb_hot_ranges",
+					map[string]interface{}{"queries_per_second": r.QueriesPerSecond}, tags)
+			}
+		}
+	}
+	return nil
+}
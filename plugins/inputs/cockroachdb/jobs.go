@@ -0,0 +1,81 @@
+package cockroachdb
+
+import (
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+// jobsPath lists the jobs of the cluster: backups, restores, imports, schema
+// changes and changefeeds.
+const jobsPath = "/_admin/v1/jobs"
+
+type jobsResponse struct {
+	Jobs []struct {
+		ID                 jsonInt64 `json:"id"`
+		Type               string    `json:"type"`
+		Status             string    `json:"status"`
+		Description        string    `json:"description"`
+		Created            time.Time `json:"created"`
+		Started            time.Time `json:"started"`
+		Finished           time.Time `json:"finished"`
+		Modified           time.Time `json:"modified"`
+		FractionCompleted  float64   `json:"fractionCompleted"`
+		Error              string    `json:"error"`
+		HighwaterTimestamp time.Time `json:"highwaterTimestamp"`
+	} `json:"jobs"`
+}
+
+// gatherJobs adds a cockroachdb_jobs point for every job of the cluster,
+// and one point per job type and status with the number of jobs.
+func (c *Cockroachdb) gatherJobs(acc telegraf.Accumulator) error {
+	var jobs jobsResponse
+	server, err := c.getAdminJSON(jobsPath, &jobs)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	type typeStatus struct{ jobType, status string }
+	counts := make(map[typeStatus]int)
+
+	for _, job := range jobs.Jobs {
+		counts[typeStatus{job.Type, job.Status}]++
+
+		fields := map[string]interface{}{
+			"fraction_completed": job.FractionCompleted,
+			"description":        job.Description,
+		}
+		if !job.Started.IsZero() {
+			end := now
+			if !job.Finished.IsZero() {
+				end = job.Finished
+			}
+			fields["running_seconds"] = end.Sub(job.Started).Seconds()
+		}
+		if !job.HighwaterTimestamp.IsZero() {
+			fields["highwater_lag_seconds"] = now.Sub(job.HighwaterTimestamp).Seconds()
+		}
+		if job.Error != "" {
+			fields["error"] = job.Error
+		}
+
+		tags := map[string]string{
+			"job_id": formatInt64(job.ID),
+			"type":   job.Type,
+			"status": job.Status,
+			"server": server,
+		}
+		acc.AddFields("cockroachdb_jobs", fields, tags)
+	}
+
+	for ts, n := range counts {
+		tags := map[string]string{
+			"type":   ts.jobType,
+			"status": ts.status,
+			"server": server,
+		}
+		acc.AddFields("cockroachdb_jobs", map[string]interface{}{"jobs": n}, tags)
+	}
+	return nil
+}
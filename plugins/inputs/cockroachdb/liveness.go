@@ -0,0 +1,233 @@
+package cockroachdb
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/influxdata/telegraf"
+)
+
+const (
+	// livenessPath reports the liveness record and status of every node.
+	livenessPath = "/_admin/v1/liveness"
+	// readyPath answers 200 only when the node accepts SQL clients.
+	readyPath = "/health?ready=1"
+)
+
+type livenessResponse struct {
+	Livenesses []struct {
+		NodeID          int       `json:"nodeId"`
+		Epoch           jsonInt64 `json:"epoch"`
+		Expiration      timestamp `json:"expiration"`
+		Draining        bool      `json:"draining"`
+		Decommissioning bool      `json:"decommissioning"`
+	} `json:"livenesses"`
+	Statuses map[string]json.RawMessage `json:"statuses"`
+}
+
+// livenessStatuses maps the numeric NodeLivenessStatus values sent by
+// releases that encode enums as integers.
+var livenessStatuses = map[int]string{
+	0: "UNKNOWN",
+	1: "DEAD",
+	2: "UNAVAILABLE",
+	3: "LIVE",
+	4: "DECOMMISSIONING",
+	5: "DECOMMISSIONED",
+}
+
+// livenessState returns the state reported for a node: live, suspect, dead,
+// decommissioning, decommissioned, draining or unknown.
+func livenessState(status json.RawMessage, draining, decommissioning bool) string {
+	var name string
+	if err := json.Unmarshal(status, &name); err != nil {
+		var n int
+		if err := json.Unmarshal(status, &n); err == nil {
+			name = livenessStatuses[n]
+		}
+	}
+	name = strings.TrimPrefix(strings.ToUpper(name), "NODE_STATUS_")
+
+	switch {
+	case name == "DECOMMISSIONED":
+		return "decommissioned"
+	case decommissioning || name == "DECOMMISSIONING":
+		return "decommissioning"
+	case name == "DEAD":
+		return "dead"
+	case name == "UNAVAILABLE":
+		return "suspect"
+	case draining:
+		return "draining"
+	case name == "LIVE":
+		return "live"
+	}
+	return "unknown"
+}
+
+// gatherLiveness adds one cockroachdb_liveness point per node known to the
+// cluster, merged with the readiness of the nodes behind the configured
+// hosts.
+func (c *Cockroachdb) gatherLiveness(acc telegraf.Accumulator) error {
+	var liveness livenessResponse
+	server, err := c.getAdminJSON(livenessPath, &liveness)
+	if err != nil {
+		return err
+	}
+
+	hosts := c.nodeHosts
+	if hosts == nil || hosts.empty() {
+		// No node status was decoded during this gather, as with the
+		// prometheus source; the addresses of the nodes are requested
+		hosts = newNodeHosts()
+		var nodes struct {
+			Nodes []*Cockroach `json:"nodes"`
+		}
+		if _, err := c.getAdminJSON(nodesPath, &nodes); err == nil {
+			for _, n := range nodes.Nodes {
+				hosts.add(n.Desc.NodeID, n.Desc.Address.AddressField, n.Desc.HTTPAddress.AddressField)
+			}
+		}
+	}
+
+	ready, unknown := c.probeReadiness(hosts)
+
+	for _, l := range liveness.Livenesses {
+		id := strconv.Itoa(l.NodeID)
+		fields := map[string]interface{}{
+			"state":           livenessState(liveness.Statuses[id], l.Draining, l.Decommissioning),
+			"epoch":           int64(l.Epoch),
+			"expiration":      int64(l.Expiration.WallTime),
+			"draining":        l.Draining,
+			"decommissioning": l.Decommissioning,
+		}
+		if r, ok := ready[l.NodeID]; ok {
+			fields["ready"] = r
+		}
+		tags := map[string]string{
+			"node_id": id,
+			"server":  server,
+		}
+		acc.AddFields("cockroachdb_liveness", fields, tags)
+	}
+
+	// Hosts that could not be matched to a node have not answered ready
+	for _, host := range unknown {
+		acc.AddFields("cockroachdb_liveness",
+			map[string]interface{}{"ready": false},
+			map[string]string{"server": host})
+	}
+	return nil
+}
+
+// probeReadiness requests the readiness of every admin host. A node that is
+// not ready answers 503, so the probe is made once, without retries. It
+// returns the readiness by node ID, and the hosts that could not be matched
+// to a node.
+func (c *Cockroachdb) probeReadiness(hosts *nodeHosts) (map[int]bool, []string) {
+	ready := make(map[int]bool)
+	var unknown []string
+	for _, base := range c.adminHosts() {
+		u, err := url.Parse(base)
+		if err != nil {
+			continue
+		}
+		host := u.Host
+
+		isReady := false
+		var health struct {
+			NodeID int `json:"nodeId"`
+		}
+		resp, err := c.client.Get(base + readyPath)
+		if err == nil {
+			isReady = resp.StatusCode == http.StatusOK
+			// Only ready nodes identify themselves in the response
+			json.NewDecoder(resp.Body).Decode(&health)
+			resp.Body.Close()
+		}
+
+		id := health.NodeID
+		if id == 0 {
+			id, _ = hosts.nodeID(host)
+		}
+		if id == 0 {
+			unknown = append(unknown, host)
+			continue
+		}
+		ready[id] = isReady
+	}
+	return ready, unknown
+}
+
+// nodeHosts maps the addresses of the nodes to their node ID, to tell which
+// node answers behind a configured host.
+type nodeHosts struct {
+	sync.Mutex
+	http      map[string]int
+	hostnames map[string]map[int]bool
+}
+
+func newNodeHosts() *nodeHosts {
+	return &nodeHosts{
+		http:      make(map[string]int),
+		hostnames: make(map[string]map[int]bool),
+	}
+}
+
+// add records the RPC and HTTP addresses of a node, as host:port.
+func (h *nodeHosts) add(id int, rpcAddr, httpAddr string) {
+	h.Lock()
+	defer h.Unlock()
+
+	if httpAddr != "" {
+		h.http[httpAddr] = id
+	}
+	for _, addr := range []string{rpcAddr, httpAddr} {
+		if addr == "" {
+			continue
+		}
+		name := hostname(addr)
+		if h.hostnames[name] == nil {
+			h.hostnames[name] = make(map[int]bool)
+		}
+		h.hostnames[name][id] = true
+	}
+}
+
+func (h *nodeHosts) empty() bool {
+	h.Lock()
+	defer h.Unlock()
+	return len(h.hostnames) == 0
+}
+
+// nodeID returns the node reached at host: the node with that HTTP address,
+// else the only node running on the same host name.
+func (h *nodeHosts) nodeID(host string) (int, bool) {
+	h.Lock()
+	defer h.Unlock()
+
+	if id, ok := h.http[host]; ok {
+		return id, true
+	}
+	ids := h.hostnames[hostname(host)]
+	if len(ids) != 1 {
+		return 0, false
+	}
+	for id := range ids {
+		return id, true
+	}
+	return 0, false
+}
+
+// hostname returns the host name of a host:port address.
+func hostname(addr string) string {
+	if h, _, err := net.SplitHostPort(addr); err == nil {
+		return h
+	}
+	return addr
+}
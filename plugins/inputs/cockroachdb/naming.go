@@ -0,0 +1,84 @@
+package cockroachdb
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+)
+
+// checkNaming validates the measurement and field naming options.
+func (c *Cockroachdb) checkNaming() error {
+	switch c.MeasurementNaming {
+	case "", "flat", "subsystem":
+	default:
+		return fmt.Errorf("unknown measurement_naming %q", c.MeasurementNaming)
+	}
+	switch c.FieldNaming {
+	case "", "underscore", "snake_case":
+	default:
+		return fmt.Errorf("unknown field_naming %q", c.FieldNaming)
+	}
+	return nil
+}
+
+// addMetrics adds the node or store metrics under measurement. With
+// measurement_naming = "subsystem" the metrics are split by the first
+// segment of their name, ex. sql.conns becomes the conns field of
+// <measurement>_sql; metrics without a subsystem stay in measurement.
+func (c *Cockroachdb) addMetrics(measurement string, fields map[string]interface{}, tags map[string]string, acc telegraf.Accumulator, t ...time.Time) {
+	if c.MeasurementNaming != "subsystem" && c.FieldNaming == "" {
+		acc.AddFields(measurement, fields, tags, t...)
+		return
+	}
+	if c.MeasurementNaming != "subsystem" {
+		renamed := make(map[string]interface{}, len(fields))
+		for k, v := range fields {
+			renamed[c.fieldName(k)] = v
+		}
+		acc.AddFields(measurement, renamed, tags, t...)
+		return
+	}
+
+	subsystems := make(map[string]map[string]interface{})
+	for k, v := range fields {
+		name, key := measurement, k
+		if i := strings.Index(k, "."); i > 0 {
+			name = measurement + "_" + underscore(k[:i])
+			key = k[i+1:]
+		}
+		if subsystems[name] == nil {
+			subsystems[name] = make(map[string]interface{})
+		}
+		subsystems[name][c.fieldName(key)] = v
+	}
+
+	for name, f := range subsystems {
+		acc.AddFields(name, f, tags, t...)
+	}
+}
+
+// fieldName normalises a metric name according to field_naming.
+func (c *Cockroachdb) fieldName(key string) string {
+	switch c.FieldNaming {
+	case "underscore":
+		return underscore(key)
+	case "snake_case":
+		return strings.Replace(internal.SnakeCase(underscore(key)), "__", "_", -1)
+	}
+	return key
+}
+
+// underscore replaces every character of s but letters and digits with an
+// underscore.
+func underscore(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return '_'
+	}, s)
+}
@@ -0,0 +1,36 @@
+package cockroachdb
+
+import (
+	"github.com/influxdata/telegraf"
+)
+
+// addPeers adds one cockroachdb_peer point per peer of the node, with the
+// RPC latency and the bytes exchanged with it.
+func addPeers(stats *Cockroach, nodeTags map[string]string, acc telegraf.Accumulator) {
+	peers := make(map[string]map[string]interface{})
+	peer := func(id string) map[string]interface{} {
+		if _, ok := peers[id]; !ok {
+			peers[id] = make(map[string]interface{})
+		}
+		return peers[id]
+	}
+
+	for id, activity := range stats.Activity {
+		fields := peer(id)
+		fields["incoming_bytes"] = int64(activity.Incoming)
+		fields["outgoing_bytes"] = int64(activity.Outgoing)
+		fields["latency"] = int64(activity.Latency)
+	}
+	// The measured latencies take precedence over the activity latency
+	for id, latency := range stats.Latencies {
+		peer(id)["latency"] = int64(latency)
+	}
+
+	for id, fields := range peers {
+		tags := map[string]string{"target_node_id": id}
+		for k, v := range nodeTags {
+			tags[k] = v
+		}
+		acc.AddFields("cockroachdb_peer", fields, tags)
+	}
+}
@@ -0,0 +1,96 @@
+package cockroachdb
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/inputs/prometheus"
+)
+
+// varsPath serves every node and store metric of a node in the Prometheus
+// text format.
+const varsPath = "/_status/vars"
+
+// gatherVars scrapes the Prometheus endpoint of the node behind the given
+// server URL, adding the typed metrics to the accumulator.
+func (c *Cockroachdb) gatherVars(s string, acc telegraf.Accumulator) error {
+	u, err := url.Parse(s)
+	if err != nil {
+		return fmt.Errorf("Unable to parse given server url %s: %s", s, err)
+	}
+
+	vars := url.URL{Scheme: u.Scheme, Host: u.Host, Path: varsPath}
+	resp, err := c.get(vars.String())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return &statusError{
+			msg:  fmt.Sprintf("Cockroachdb responded with unexepcted status code %d from %s", resp.StatusCode, vars.String()),
+			code: resp.StatusCode,
+		}
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading body: %s", err)
+	}
+
+	metrics, err := prometheus.Parse(body, resp.Header)
+	if err != nil {
+		return &decodeError{msg: fmt.Sprintf("error reading metrics for %s: %s", vars.String(), err)}
+	}
+
+	// The node identity is reported as the value and labels of node_id
+	nodeTags := map[string]string{
+		"server": u.Host,
+	}
+	for _, m := range metrics {
+		if m.Name() != "node_id" {
+			continue
+		}
+		if v, ok := m.GetField("gauge"); ok {
+			nodeTags["node_id"] = strconv.FormatFloat(v.(float64), 'f', -1, 64)
+		}
+		if addr, ok := m.GetTag("advertise_addr"); ok {
+			nodeTags["addressField"] = addr
+		}
+	}
+
+	for _, m := range metrics {
+		if !c.metricFilter.Match(m.Name()) {
+			continue
+		}
+
+		tags := m.Tags()
+		if store, ok := tags["store"]; ok {
+			delete(tags, "store")
+			tags["store_id"] = store
+		}
+		for k, v := range nodeTags {
+			tags[k] = v
+		}
+
+		name := "cockroachdb_" + m.Name()
+		switch m.Type() {
+		case telegraf.Counter:
+			acc.AddCounter(name, m.Fields(), tags, m.Time())
+		case telegraf.Gauge:
+			acc.AddGauge(name, m.Fields(), tags, m.Time())
+		case telegraf.Summary:
+			acc.AddSummary(name, m.Fields(), tags, m.Time())
+		case telegraf.Histogram:
+			acc.AddHistogram(name, m.Fields(), tags, m.Time())
+		default:
+			acc.AddFields(name, m.Fields(), tags, m.Time())
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,117 @@
+package cockroachdb
+
+import (
+	"sort"
+
+	"github.com/influxdata/telegraf"
+)
+
+// problemRangesPath reports the problem ranges of every node in the cluster.
+const problemRangesPath = "/_status/problemranges"
+
+type problemRangesResponse struct {
+	ProblemsByNodeID map[string]struct {
+		ErrorMessage                     string      `json:"errorMessage"`
+		UnavailableRangeIDs              []jsonInt64 `json:"unavailableRangeIds"`
+		RaftLeaderNotLeaseHolderRangeIDs []jsonInt64 `json:"raftLeaderNotLeaseHolderRangeIds"`
+		NoRaftLeaderRangeIDs             []jsonInt64 `json:"noRaftLeaderRangeIds"`
+		NoLeaseRangeIDs                  []jsonInt64 `json:"noLeaseRangeIds"`
+		UnderreplicatedRangeIDs          []jsonInt64 `json:"underreplicatedRangeIds"`
+		OverreplicatedRangeIDs           []jsonInt64 `json:"overreplicatedRangeIds"`
+		QuiescentEqualsTickingRangeIDs   []jsonInt64 `json:"quiescentEqualsTickingRangeIds"`
+		RaftLogTooLargeRangeIDs          []jsonInt64 `json:"raftLogTooLargeRangeIds"`
+	} `json:"problemsByNodeId"`
+}
+
+// storeRangeFields maps the store metrics reported in cockroachdb_ranges to
+// their field names.
+var storeRangeFields = map[string]string{
+	"ranges":                 "ranges",
+	"ranges.unavailable":     "unavailable",
+	"ranges.underreplicated": "underreplicated",
+	"ranges.overreplicated":  "overreplicated",
+	"replicas.quiescent":     "quiescent",
+}
+
+// gatherProblemRanges adds one cockroachdb_ranges point per node with the
+// number of problem ranges in each category and, up to problem_range_limit,
+// one point per problem range.
+func (c *Cockroachdb) gatherProblemRanges(acc telegraf.Accumulator) error {
+	var problems problemRangesResponse
+	server, err := c.getAdminJSON(problemRangesPath, &problems)
+	if err != nil {
+		return err
+	}
+
+	nodeIDs := make([]string, 0, len(problems.ProblemsByNodeID))
+	for id := range problems.ProblemsByNodeID {
+		nodeIDs = append(nodeIDs, id)
+	}
+	sort.Strings(nodeIDs)
+
+	listed := 0
+	for _, id := range nodeIDs {
+		p := problems.ProblemsByNodeID[id]
+		tags := map[string]string{
+			"node_id": id,
+			"server":  server,
+		}
+
+		categories := []struct {
+			name string
+			ids  []jsonInt64
+		}{
+			{"unavailable", p.UnavailableRangeIDs},
+			{"underreplicated", p.UnderreplicatedRangeIDs},
+			{"overreplicated", p.OverreplicatedRangeIDs},
+			{"no_raft_leader", p.NoRaftLeaderRangeIDs},
+			{"no_lease", p.NoLeaseRangeIDs},
+			{"raft_leader_not_leaseholder", p.RaftLeaderNotLeaseHolderRangeIDs},
+			{"quiescent_equals_ticking", p.QuiescentEqualsTickingRangeIDs},
+			{"raft_log_too_large", p.RaftLogTooLargeRangeIDs},
+		}
+
+		// Counts are float64 like the store range metrics they share
+		// their field names with.
+		fields := make(map[string]interface{})
+		for _, category := range categories {
+			fields[category.name] = float64(len(category.ids))
+		}
+		if p.ErrorMessage != "" {
+			fields["error"] = p.ErrorMessage
+		}
+		acc.AddFields("cockroachdb_ranges", fields, tags)
+
+		for _, category := range categories {
+			for _, rangeID := range category.ids {
+				if listed >= c.ProblemRangeLimit {
+					break
+				}
+				listed++
+
+				rangeTags := map[string]string{
+					"problem":  category.name,
+					"range_id": formatInt64(rangeID),
+				}
+				for k, v := range tags {
+					rangeTags[k] = v
+				}
+				acc.AddFields("cockroachdb_ranges", map[string]interface{}{"problem_range": 1}, rangeTags)
+			}
+		}
+	}
+	return nil
+}
+
+// addStoreRanges adds the range counts of a store to cockroachdb_ranges.
+func addStoreRanges(metrics map[string]interface{}, tags map[string]string, acc telegraf.Accumulator) {
+	fields := make(map[string]interface{})
+	for metric, field := range storeRangeFields {
+		if v, ok := metrics[metric]; ok {
+			fields[field] = v
+		}
+	}
+	if len(fields) > 0 {
+		acc.AddFields("cockroachdb_ranges", fields, tags)
+	}
+}
@@ -0,0 +1,131 @@
+package cockroachdb
+
+import (
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+// clusterPath reports the ID of the cluster.
+const clusterPath = "/_admin/v1/cluster"
+
+// defaultMaxOffset is the maximum clock offset of nodes started without
+// --max-offset.
+const defaultMaxOffset = 500 * time.Millisecond
+
+// clusterRollup accumulates the status of every node reached during a
+// gather.
+type clusterRollup struct {
+	sync.Mutex
+
+	nodes            map[int]bool
+	versions         map[string]bool
+	capacity         int64
+	available        int64
+	used             int64
+	clockOffset      float64
+	clockOffsetRatio float64
+	hasClockOffset   bool
+}
+
+func newClusterRollup() *clusterRollup {
+	return &clusterRollup{
+		nodes:    make(map[int]bool),
+		versions: make(map[string]bool),
+	}
+}
+
+// addNode adds a node to the rollup. Nodes reported more than once, through
+// both servers and seeds, are only counted once.
+func (r *clusterRollup) addNode(stats *Cockroach, version string) {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.nodes[stats.Desc.NodeID] {
+		return
+	}
+	r.nodes[stats.Desc.NodeID] = true
+	r.versions[version] = true
+
+	for _, store := range stats.StoreStatuses {
+		r.capacity += int64(store.Desc.Capacity.Capacity)
+		r.available += int64(store.Desc.Capacity.Available)
+		r.used += int64(store.Desc.Capacity.Used)
+	}
+
+	if v, ok := toFloat(stats.Metrics["clock-offset.meannanos"]); ok {
+		offset := math.Abs(v)
+		if !r.hasClockOffset || offset > r.clockOffset {
+			r.clockOffset = offset
+		}
+		ratio := offset / float64(maxOffset(stats.Args))
+		if !r.hasClockOffset || ratio > r.clockOffsetRatio {
+			r.clockOffsetRatio = ratio
+		}
+		r.hasClockOffset = true
+	}
+}
+
+// gatherRollup adds the cockroachdb_cluster point computed from the nodes
+// reached during this gather. The clusterAccumulator of the gather tags it
+// with the cluster identity.
+func (c *Cockroachdb) gatherRollup(r *clusterRollup, acc telegraf.Accumulator) {
+	r.Lock()
+	defer r.Unlock()
+
+	fields := map[string]interface{}{
+		"nodes":              len(r.nodes),
+		"versions":           len(r.versions),
+		"capacity":           r.capacity,
+		"capacity_available": r.available,
+		"capacity_used":      r.used,
+	}
+	if r.used+r.available > 0 {
+		fields["capacity_used_percent"] = 100 * float64(r.used) / float64(r.used+r.available)
+	}
+	if c.ExpectedNodes > 0 {
+		fields["nodes_expected"] = c.ExpectedNodes
+		fields["nodes_missing"] = c.ExpectedNodes - len(r.nodes)
+	}
+	if r.hasClockOffset {
+		fields["clock_offset_max_nanos"] = r.clockOffset
+		fields["clock_offset_max_ratio"] = r.clockOffsetRatio
+	}
+
+	acc.AddFields("cockroachdb_cluster", fields, nil)
+}
+
+// maxOffset returns the maximum clock offset a node was started with.
+func maxOffset(args []string) time.Duration {
+	for i, arg := range args {
+		var value string
+		switch {
+		case strings.HasPrefix(arg, "--max-offset="):
+			value = strings.TrimPrefix(arg, "--max-offset=")
+		case arg == "--max-offset" && i+1 < len(args):
+			value = args[i+1]
+		default:
+			continue
+		}
+		if d, err := time.ParseDuration(value); err == nil && d > 0 {
+			return d
+		}
+	}
+	return defaultMaxOffset
+}
+
+// toFloat converts a decoded metric value to a float64.
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
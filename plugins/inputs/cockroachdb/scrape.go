@@ -0,0 +1,83 @@
+package cockroachdb
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+// gatherServers gathers every configured server, running up to Workers
+// scrapes at a time. The outcome of each scrape is reported in
+// cockroachdb_scrape.
+func (c *Cockroachdb) gatherServers(gather func(string, telegraf.Accumulator) error, acc telegraf.Accumulator) {
+	workers := c.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	sem := make(chan struct{}, workers)
+
+	var wg sync.WaitGroup
+	for _, s := range c.Servers {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(s string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			start := time.Now()
+			err := gather(s, acc)
+			addScrape(serverHost(s), time.Since(start), err, acc)
+			acc.AddError(err)
+		}(s)
+	}
+	wg.Wait()
+}
+
+// addScrape adds the status of a single request to a node.
+func addScrape(server string, duration time.Duration, err error, acc telegraf.Accumulator) {
+	fields := map[string]interface{}{
+		"up":       1,
+		"duration": duration.Seconds(),
+	}
+	if err != nil {
+		fields["up"] = 0
+		fields["error_class"] = errorClass(err)
+	}
+	acc.AddFields("cockroachdb_scrape", fields, map[string]string{"server": server})
+}
+
+// errorClass reduces a scrape error to one of timeout, connection_failed,
+// auth, http_status, decode or other.
+func errorClass(err error) string {
+	switch e := err.(type) {
+	case *statusError:
+		if e.code == http.StatusUnauthorized || e.code == http.StatusForbidden {
+			return "auth"
+		}
+		return "http_status"
+	case *decodeError:
+		return "decode"
+	case net.Error:
+		if e.Timeout() {
+			return "timeout"
+		}
+		return "connection_failed"
+	}
+	return "other"
+}
+
+// serverHost returns the host of a server URL, or the URL itself when it
+// cannot be parsed.
+func serverHost(s string) string {
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" {
+		return s
+	}
+	return u.Host
+}
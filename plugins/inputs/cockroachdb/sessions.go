@@ -0,0 +1,167 @@
+package cockroachdb
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+const (
+	// sessionsPath lists the open SQL sessions of every node, with their
+	// active transaction and queries.
+	sessionsPath = "/_status/sessions"
+	// localSessionsPath lists the open SQL sessions of the node answering.
+	localSessionsPath = "/_status/local_sessions"
+)
+
+type sessionsResponse struct {
+	Sessions []struct {
+		NodeID          int    `json:"nodeId"`
+		Username        string `json:"username"`
+		ApplicationName string `json:"applicationName"`
+		ActiveQueries   []struct {
+			ID            string    `json:"id"`
+			Start         time.Time `json:"start"`
+			SQL           string    `json:"sql"`
+			IsDistributed bool      `json:"isDistributed"`
+			Phase         string    `json:"phase"`
+		} `json:"activeQueries"`
+		ActiveTxn *struct {
+			Start time.Time `json:"start"`
+		} `json:"activeTxn"`
+	} `json:"sessions"`
+	Errors []struct {
+		NodeID  int    `json:"nodeId"`
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// sessionGroup holds the sessions of one application and user on a node.
+type sessionGroup struct {
+	sessions     int
+	transactions int
+	queries      int
+	oldestTxn    time.Time
+	oldestQuery  time.Time
+}
+
+// gatherSessions adds a cockroachdb_sessions point per node, application
+// and user with the number of open sessions, active transactions and
+// queries. Queries running for longer than LongQueryThreshold are reported
+// one point each in cockroachdb_long_query.
+func (c *Cockroachdb) gatherSessions(acc telegraf.Accumulator) error {
+	if c.SessionsLocal {
+		return c.gatherLocalSessions(acc)
+	}
+
+	var resp sessionsResponse
+	server, err := c.getAdminJSON(sessionsPath, &resp)
+	if err != nil {
+		return err
+	}
+	c.addSessions(&resp, server, acc)
+	return nil
+}
+
+// gatherLocalSessions asks every admin host for its own sessions, so that the
+// sessions of a node are reported by the node itself rather than through the
+// fan-out of a single node.
+func (c *Cockroachdb) gatherLocalSessions(acc telegraf.Accumulator) error {
+	for _, base := range c.adminHosts() {
+		u, err := url.Parse(base)
+		if err != nil {
+			continue
+		}
+
+		var resp sessionsResponse
+		if err := c.getJSON(base+localSessionsPath, &resp); err != nil {
+			acc.AddError(err)
+			continue
+		}
+		c.addSessions(&resp, u.Host, acc)
+	}
+	return nil
+}
+
+// addSessions adds the sessions listed by server.
+func (c *Cockroachdb) addSessions(resp *sessionsResponse, server string, acc telegraf.Accumulator) {
+	// Nodes that failed to answer are missing from the counts
+	for _, e := range resp.Errors {
+		acc.AddError(fmt.Errorf("sessions of node %d: %s", e.NodeID, e.Message))
+	}
+
+	now := time.Now()
+	type groupKey struct {
+		nodeID    int
+		app, user string
+	}
+	groups := make(map[groupKey]*sessionGroup)
+
+	for _, session := range resp.Sessions {
+		k := groupKey{session.NodeID, session.ApplicationName, session.Username}
+		g, ok := groups[k]
+		if !ok {
+			g = &sessionGroup{}
+			groups[k] = g
+		}
+
+		g.sessions++
+		if session.ActiveTxn != nil {
+			g.transactions++
+			if g.oldestTxn.IsZero() || session.ActiveTxn.Start.Before(g.oldestTxn) {
+				g.oldestTxn = session.ActiveTxn.Start
+			}
+		}
+
+		for _, q := range session.ActiveQueries {
+			g.queries++
+			if g.oldestQuery.IsZero() || q.Start.Before(g.oldestQuery) {
+				g.oldestQuery = q.Start
+			}
+
+			running := now.Sub(q.Start)
+			if c.LongQueryThreshold.Duration <= 0 || running < c.LongQueryThreshold.Duration {
+				continue
+			}
+			fields := map[string]interface{}{
+				"running_seconds": running.Seconds(),
+				"query":           q.SQL,
+				"distributed":     q.IsDistributed,
+			}
+			tags := map[string]string{
+				"node_id":  strconv.Itoa(session.NodeID),
+				"app":      session.ApplicationName,
+				"user":     session.Username,
+				"query_id": q.ID,
+				"phase":    q.Phase,
+				"server":   server,
+			}
+			acc.AddFields("cockroachdb_long_query", fields, tags)
+		}
+	}
+
+	for k, g := range groups {
+		fields := map[string]interface{}{
+			"sessions":     g.sessions,
+			"transactions": g.transactions,
+			"queries":      g.queries,
+		}
+		if !g.oldestTxn.IsZero() {
+			fields["oldest_transaction_seconds"] = now.Sub(g.oldestTxn).Seconds()
+		}
+		if !g.oldestQuery.IsZero() {
+			fields["oldest_query_seconds"] = now.Sub(g.oldestQuery).Seconds()
+		}
+
+		tags := map[string]string{
+			"node_id": strconv.Itoa(k.nodeID),
+			"app":     k.app,
+			"user":    k.user,
+			"server":  server,
+		}
+		acc.AddFields("cockroachdb_sessions", fields, tags)
+	}
+}
@@ -0,0 +1,165 @@
+package cockroachdb
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/inputs/postgresql"
+)
+
+// SQLQuery is a query run over pgwire, in the style of the
+// postgresql_extensible query tables. Columns listed in Tagvalue become tags,
+// all other non-null columns become fields.
+type SQLQuery struct {
+	Sqlquery    string
+	Tagvalue    string
+	Measurement string
+}
+
+// builtinQueries are run in SQL mode unless sql_builtin_queries is disabled.
+// sum() over INT columns returns DECIMAL, which the driver decodes as text,
+// so aggregates are cast to INT8 or FLOAT8.
+var builtinQueries = []SQLQuery{
+	{
+		Measurement: "cockroachdb_sql_statements",
+		Tagvalue:    "node_id,application_name",
+		Sqlquery: `SELECT node_id, application_name,
+  sum(count)::INT8 AS count,
+  sum(first_attempt_count)::INT8 AS first_attempt_count,
+  max(max_retries)::INT8 AS max_retries,
+  (sum(service_lat_avg * count::FLOAT8)::FLOAT8 / sum(count)::FLOAT8) AS service_lat_avg
+FROM crdb_internal.node_statement_statistics
+GROUP BY node_id, application_name`,
+	},
+	{
+		Measurement: "cockroachdb_sql_ranges",
+		Tagvalue:    "database_name,table_name",
+		Sqlquery: `SELECT database_name, table_name,
+  count(*) AS ranges,
+  sum(array_length(replicas, 1))::INT8 AS replicas
+FROM crdb_internal.ranges
+GROUP BY database_name, table_name`,
+	},
+	{
+		Measurement: "cockroachdb_sql_jobs",
+		Tagvalue:    "job_type,status",
+		Sqlquery: `SELECT job_type, status, count(*) AS jobs
+FROM crdb_internal.jobs
+GROUP BY job_type, status`,
+	},
+	{
+		Measurement: "cockroachdb_sql_liveness",
+		Tagvalue:    "node_id",
+		Sqlquery: `SELECT node_id, epoch, draining, decommissioning
+FROM crdb_internal.gossip_liveness`,
+	},
+}
+
+// gatherSQL runs the built-in and configured queries over pgwire. The
+// connection is opened and closed within the gather, as the plugin has no
+// Stop to release it on.
+func (c *Cockroachdb) gatherSQL(acc telegraf.Accumulator) error {
+	svc := &postgresql.Service{
+		Address: c.SQLAddress,
+		MaxIdle: 1,
+		MaxOpen: 1,
+	}
+	if err := svc.Start(acc); err != nil {
+		return err
+	}
+	defer svc.Stop()
+
+	server, err := svc.SanitizedAddress()
+	if err != nil {
+		return err
+	}
+
+	var queries []SQLQuery
+	if c.SQLBuiltinQueries {
+		queries = append(queries, builtinQueries...)
+	}
+	queries = append(queries, c.SQLQuery...)
+
+	for _, q := range queries {
+		if err := runQuery(svc.DB, q, server, acc); err != nil {
+			acc.AddError(fmt.Errorf("query %q: %s", q.Sqlquery, err))
+		}
+	}
+	return nil
+}
+
+func runQuery(db *sql.DB, q SQLQuery, server string, acc telegraf.Accumulator) error {
+	rows, err := db.Query(q.Sqlquery)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	measurement := q.Measurement
+	if measurement == "" {
+		measurement = "cockroachdb_sql"
+	}
+
+	var tagColumns []string
+	if q.Tagvalue != "" {
+		tagColumns = strings.Split(q.Tagvalue, ",")
+	}
+
+	for rows.Next() {
+		tags, fields, err := scanRow(rows, columns, tagColumns)
+		if err != nil {
+			return err
+		}
+		tags["server"] = server
+		acc.AddFields(measurement, fields, tags)
+	}
+	return rows.Err()
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanRow splits a result row into tags and fields. Null columns are
+// skipped and byte slices are converted to strings.
+func scanRow(row scanner, columns []string, tagColumns []string) (map[string]string, map[string]interface{}, error) {
+	values := make([]interface{}, len(columns))
+	pointers := make([]interface{}, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	if err := row.Scan(pointers...); err != nil {
+		return nil, nil, err
+	}
+
+	isTag := make(map[string]bool, len(tagColumns))
+	for _, col := range tagColumns {
+		isTag[strings.TrimSpace(col)] = true
+	}
+
+	tags := make(map[string]string)
+	fields := make(map[string]interface{})
+	for i, col := range columns {
+		v := values[i]
+		if v == nil {
+			continue
+		}
+		if b, ok := v.([]byte); ok {
+			v = string(b)
+		}
+
+		if isTag[col] {
+			tags[col] = fmt.Sprint(v)
+			continue
+		}
+		fields[col] = v
+	}
+	return tags, fields, nil
+}
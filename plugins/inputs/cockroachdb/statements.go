@@ -0,0 +1,183 @@
+package cockroachdb
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+)
+
+// statementsPath lists the statement statistics collected by every node
+// since the last reset, keyed by fingerprint, application and plan options.
+const statementsPath = "/_status/statements"
+
+// internalAppPrefix marks the application names of internal statements.
+const internalAppPrefix = "$ internal"
+
+type numericStat struct {
+	Mean float64 `json:"mean"`
+}
+
+type statementsResponse struct {
+	Statements []struct {
+		Key struct {
+			KeyData struct {
+				Query    string `json:"query"`
+				App      string `json:"app"`
+				Database string `json:"database"`
+				Failed   bool   `json:"failed"`
+			} `json:"keyData"`
+		} `json:"key"`
+		Stats struct {
+			Count             jsonInt64   `json:"count"`
+			FirstAttemptCount jsonInt64   `json:"firstAttemptCount"`
+			MaxRetries        jsonInt64   `json:"maxRetries"`
+			NumRows           numericStat `json:"numRows"`
+			ServiceLat        numericStat `json:"serviceLat"`
+			LatencyInfo       struct {
+				Max float64 `json:"max"`
+			} `json:"latencyInfo"`
+		} `json:"stats"`
+	} `json:"statements"`
+}
+
+// statementStats aggregates the statistics of a fingerprint over every node
+// and plan option.
+type statementStats struct {
+	query       string
+	app         string
+	database    string
+	count       int64
+	errors      int64
+	retries     int64
+	maxRetries  int64
+	rows        float64
+	latencySum  float64
+	latencyMax  float64
+	latencyInfo bool
+}
+
+func (s *statementStats) meanLatency() float64 {
+	if s.count == 0 {
+		return 0
+	}
+	return s.latencySum / float64(s.count)
+}
+
+// statementSortKeys maps the statements_sort_by values to the statistic
+// fingerprints are ranked by, in descending order.
+var statementSortKeys = map[string]func(*statementStats) float64{
+	"count":           func(s *statementStats) float64 { return float64(s.count) },
+	"errors":          func(s *statementStats) float64 { return float64(s.errors) },
+	"retries":         func(s *statementStats) float64 { return float64(s.retries) },
+	"rows":            func(s *statementStats) float64 { return s.rows },
+	"service_latency": (*statementStats).meanLatency,
+	"max_latency":     func(s *statementStats) float64 { return s.latencyMax },
+	"total_latency":   func(s *statementStats) float64 { return s.latencySum },
+}
+
+// gatherStatements adds a cockroachdb_statements point for the top
+// StatementsLimit statement fingerprints of the cluster, ranked by
+// StatementsSortBy. Internal statements are skipped.
+func (c *Cockroachdb) gatherStatements(acc telegraf.Accumulator) error {
+	sortBy := c.StatementsSortBy
+	if sortBy == "" {
+		sortBy = "total_latency"
+	}
+	key, ok := statementSortKeys[sortBy]
+	if !ok {
+		return fmt.Errorf("unknown statements_sort_by %q", sortBy)
+	}
+
+	var resp statementsResponse
+	server, err := c.getAdminJSON(statementsPath, &resp)
+	if err != nil {
+		return err
+	}
+
+	type fingerprint struct{ query, app, database string }
+	byFingerprint := make(map[fingerprint]*statementStats)
+	var stats []*statementStats
+
+	for _, stmt := range resp.Statements {
+		k := stmt.Key.KeyData
+		if strings.HasPrefix(k.App, internalAppPrefix) {
+			continue
+		}
+
+		fp := fingerprint{k.Query, k.App, k.Database}
+		s, ok := byFingerprint[fp]
+		if !ok {
+			s = &statementStats{query: k.Query, app: k.App, database: k.Database}
+			byFingerprint[fp] = s
+			stats = append(stats, s)
+		}
+
+		count := int64(stmt.Stats.Count)
+		s.count += count
+		if k.Failed {
+			s.errors += count
+		}
+		s.retries += count - int64(stmt.Stats.FirstAttemptCount)
+		if int64(stmt.Stats.MaxRetries) > s.maxRetries {
+			s.maxRetries = int64(stmt.Stats.MaxRetries)
+		}
+		s.rows += stmt.Stats.NumRows.Mean * float64(count)
+		s.latencySum += stmt.Stats.ServiceLat.Mean * float64(count)
+		if stmt.Stats.LatencyInfo.Max > 0 {
+			s.latencyInfo = true
+			if stmt.Stats.LatencyInfo.Max > s.latencyMax {
+				s.latencyMax = stmt.Stats.LatencyInfo.Max
+			}
+		}
+	}
+
+	sort.SliceStable(stats, func(i, j int) bool {
+		return key(stats[i]) > key(stats[j])
+	})
+	if c.StatementsLimit > 0 && len(stats) > c.StatementsLimit {
+		stats = stats[:c.StatementsLimit]
+	}
+
+	for _, s := range stats {
+		fields := map[string]interface{}{
+			"count":                s.count,
+			"errors":               s.errors,
+			"retries":              s.retries,
+			"max_retries":          s.maxRetries,
+			"rows_mean":            0.0,
+			"service_latency_mean": s.meanLatency(),
+		}
+		if s.count > 0 {
+			fields["rows_mean"] = s.rows / float64(s.count)
+		}
+		if s.latencyInfo {
+			fields["service_latency_max"] = s.latencyMax
+		}
+		if c.StatementsQueryText {
+			fields["query"] = s.query
+		}
+
+		tags := map[string]string{
+			"app":         s.app,
+			"fingerprint": fingerprintHash(s.query),
+			"server":      server,
+		}
+		if s.database != "" {
+			tags["database"] = s.database
+		}
+		acc.AddFields("cockroachdb_statements", fields, tags)
+	}
+	return nil
+}
+
+// fingerprintHash returns a short, stable identifier of a statement
+// fingerprint, usable as a tag in place of the query text.
+func fingerprintHash(query string) string {
+	h := fnv.New64a()
+	h.Write([]byte(query))
+	return strconv.FormatUint(h.Sum64(), 16)
+}
@@ -0,0 +1,60 @@
+package cockroachdb
+
+import (
+	"regexp"
+	"strings"
+)
+
+// percentileKey matches the flattened histogram fields CockroachDB reports,
+// ex. sql.service.latency-p99.9 or sql.mem.admin.max-max.
+var percentileKey = regexp.MustCompile(`^(.+)-(p[0-9]{1,2}(?:\.[0-9]+)?|max)$`)
+
+// splitSummaries removes the flattened percentile fields from fields and
+// returns them grouped by histogram family, keyed by quantile. The maximum is
+// reported as the 1 quantile.
+func splitSummaries(fields map[string]interface{}) map[string]map[string]interface{} {
+	families := make(map[string]map[string]interface{})
+	for k, v := range fields {
+		match := percentileKey.FindStringSubmatch(k)
+		if match == nil {
+			continue
+		}
+
+		family, ok := families[match[1]]
+		if !ok {
+			family = make(map[string]interface{})
+			families[match[1]] = family
+		}
+		family[quantile(match[2])] = v
+		delete(fields, k)
+	}
+	return families
+}
+
+// quantile converts a percentile suffix below 100, such as p99.9, into the
+// quantile field key 0.999. The decimal point is shifted on the string to avoid
+// floating point noise in the key.
+func quantile(p string) string {
+	if p == "max" {
+		return "1"
+	}
+
+	whole, frac := strings.TrimPrefix(p, "p"), ""
+	if i := strings.Index(whole, "."); i >= 0 {
+		whole, frac = whole[:i], whole[i+1:]
+	}
+	for len(whole) < 2 {
+		whole = "0" + whole
+	}
+
+	digits := strings.TrimRight(whole+frac, "0")
+	if digits == "" {
+		return "0"
+	}
+	return "0." + digits
+}
+
+// summaryName returns the measurement name of a histogram family.
+func summaryName(family string) string {
+	return "cockroachdb_" + strings.NewReplacer(".", "_", "-", "_").Replace(family)
+}
@@ -0,0 +1,110 @@
+package cockroachdb
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+)
+
+// databasesPath lists the databases of the cluster, and with a database name
+// appended, the tables of that database.
+const databasesPath = "/_admin/v1/databases"
+
+type databasesResponse struct {
+	Databases []string `json:"databases"`
+}
+
+type databaseResponse struct {
+	TableNames []string `json:"tableNames"`
+}
+
+type tableStatsResponse struct {
+	RangeCount           jsonInt64 `json:"rangeCount"`
+	ReplicaCount         jsonInt64 `json:"replicaCount"`
+	NodeCount            jsonInt64 `json:"nodeCount"`
+	ApproximateDiskBytes jsonInt64 `json:"approximateDiskBytes"`
+	Stats                struct {
+		LiveBytes jsonInt64 `json:"liveBytes"`
+	} `json:"stats"`
+	MissingNodes []struct {
+		NodeID       string `json:"nodeId"`
+		ErrorMessage string `json:"errorMessage"`
+	} `json:"missingNodes"`
+}
+
+// gatherTables adds a cockroachdb_table point with the size of every table
+// matching the database and table filters. The statistics are requested at
+// most once per TableStatsInterval.
+func (c *Cockroachdb) gatherTables(acc telegraf.Accumulator) error {
+	now := time.Now()
+	if !c.lastTableStats.IsZero() && now.Sub(c.lastTableStats) < c.TableStatsInterval.Duration {
+		return nil
+	}
+
+	if c.databaseFilter == nil {
+		f, err := filter.NewIncludeExcludeFilter(c.DatabaseInclude, c.DatabaseExclude)
+		if err != nil {
+			return fmt.Errorf("unable to compile database filters: %s", err)
+		}
+		c.databaseFilter = f
+	}
+	if c.tableFilter == nil {
+		f, err := filter.NewIncludeExcludeFilter(c.TableInclude, c.TableExclude)
+		if err != nil {
+			return fmt.Errorf("unable to compile table filters: %s", err)
+		}
+		c.tableFilter = f
+	}
+
+	var databases databasesResponse
+	if _, err := c.getAdminJSON(databasesPath, &databases); err != nil {
+		return err
+	}
+	c.lastTableStats = now
+
+	for _, db := range databases.Databases {
+		if !c.databaseFilter.Match(db) {
+			continue
+		}
+
+		var database databaseResponse
+		dbPath := databasesPath + "/" + url.PathEscape(db)
+		if _, err := c.getAdminJSON(dbPath, &database); err != nil {
+			acc.AddError(err)
+			continue
+		}
+
+		for _, table := range database.TableNames {
+			if !c.tableFilter.Match(table) {
+				continue
+			}
+
+			var stats tableStatsResponse
+			statsPath := dbPath + "/tables/" + url.PathEscape(table) + "/stats"
+			server, err := c.getAdminJSON(statsPath, &stats)
+			if err != nil {
+				acc.AddError(err)
+				continue
+			}
+
+			fields := map[string]interface{}{
+				"range_count":            int64(stats.RangeCount),
+				"replica_count":          int64(stats.ReplicaCount),
+				"node_count":             int64(stats.NodeCount),
+				"approximate_disk_bytes": int64(stats.ApproximateDiskBytes),
+				"live_bytes":             int64(stats.Stats.LiveBytes),
+				"missing_nodes":          len(stats.MissingNodes),
+			}
+			tags := map[string]string{
+				"database": db,
+				"table":    table,
+				"server":   server,
+			}
+			acc.AddFields("cockroachdb_table", fields, tags)
+		}
+	}
+	return nil
+}
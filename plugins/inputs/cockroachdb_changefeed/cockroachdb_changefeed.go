@@ -0,0 +1,516 @@
+package cockroachdb_changefeed
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	tlsint "github.com/influxdata/telegraf/internal/tls"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const (
+	// DEFAULT_MAX_BODY_SIZE is the default maximum request body size, in bytes.
+	// if the request body is over this size, we will return an HTTP 413 error.
+	// 32 MB
+	DEFAULT_MAX_BODY_SIZE = 32 * 1024 * 1024
+)
+
+type TimeFunc func() time.Time
+
+type CockroachdbChangefeed struct {
+	ServiceAddress string
+	Path           string
+	ReadTimeout    internal.Duration
+	WriteTimeout   internal.Duration
+	MaxBodySize    int64
+	Port           int
+
+	tlsint.ServerConfig
+
+	BasicUsername string
+	BasicPassword string
+
+	// Mapping of the row columns
+	Measurement     string
+	TagColumns      []string `toml:"tag_columns"`
+	FieldColumns    []string `toml:"field_columns"`
+	IntegerColumns  []string `toml:"integer_columns"`
+	TimestampColumn string   `toml:"timestamp_column"`
+	TimestampFormat string   `toml:"timestamp_format"`
+
+	TimeFunc
+
+	mu sync.Mutex
+	wg sync.WaitGroup
+
+	listener net.Listener
+
+	acc telegraf.Accumulator
+
+	// resolved is the latest resolved timestamp of each feed, guarded by
+	// resolvedMu.
+	resolved   map[string]time.Time
+	resolvedMu sync.Mutex
+}
+
+// webhookBody is a batch of rows, or a resolved timestamp, posted by the
+// webhook sink of a changefeed.
+type webhookBody struct {
+	Payload []struct {
+		After   json.RawMessage `json:"after"`
+		Key     json.RawMessage `json:"key"`
+		Topic   string          `json:"topic"`
+		Updated string          `json:"updated"`
+	} `json:"payload"`
+	Length   int    `json:"length"`
+	Resolved string `json:"resolved"`
+}
+
+const sampleConfig = `
+  ## Address and port to host the webhook listener on
+  service_address = ":8188"
+
+  ## Path of the webhook sink URL. Sub-paths name the changefeed in the feed
+  ## tag, ex. CREATE CHANGEFEED FOR TABLE orders INTO
+  ## 'webhook-https://telegraf:8188/changefeed/orders?insecure_tls_skip_verify=true'
+  ## WITH updated, resolved = '10s'
+  path = "/changefeed"
+
+  ## maximum duration before timing out read of the request
+  read_timeout = "10s"
+  ## maximum duration before timing out write of the response
+  write_timeout = "10s"
+
+  ## Maximum allowed http request body size in bytes.
+  ## 0 means to use the default of 33,554,432 bytes (32 mebibytes)
+  max_body_size = 0
+
+  ## The webhook sink only posts to HTTPS URLs. Add service certificate and
+  ## key, and optionally one or more allowed client CA certificate file names
+  ## to enable mutually authenticated TLS connections.
+  tls_cert = "/etc/telegraf/cert.pem"
+  tls_key = "/etc/telegraf/key.pem"
+  # tls_allowed_cacerts = ["/etc/telegraf/clientca.pem"]
+
+  ## Optional username and password to accept for HTTP basic authentication.
+  # basic_username = "foobar"
+  # basic_password = "barfoo"
+
+  ## Measurement name of the rows.
+  measurement = "cockroachdb_changefeed"
+
+  ## Row columns added as tags. Other columns are added as fields, unless
+  ## field_columns lists the columns to keep.
+  tag_columns = []
+  # field_columns = []
+
+  ## Number columns added as integer fields. Other numbers are added as
+  ## floats, so that FLOAT and DECIMAL columns keep one type.
+  # integer_columns = []
+
+  ## Column holding the time of the row. Without it, rows are timestamped
+  ## with their MVCC timestamp when the changefeed is created WITH updated,
+  ## or the time they are received.
+  # timestamp_column = ""
+  ## Format of the timestamp column: "unix", "unix_ms", "unix_us", "unix_ns"
+  ## for numbers, or a Go reference time layout for strings. Strings default
+  ## to the JSON encoding of TIMESTAMP and TIMESTAMPTZ columns.
+  # timestamp_format = ""
+`
+
+func (c *CockroachdbChangefeed) SampleConfig() string {
+	return sampleConfig
+}
+
+func (c *CockroachdbChangefeed) Description() string {
+	return "CockroachDB changefeed webhook sink listener"
+}
+
+// Gather adds the lag of every changefeed that sent a resolved timestamp, so
+// that the lag keeps growing while a stalled changefeed sends none.
+func (c *CockroachdbChangefeed) Gather(acc telegraf.Accumulator) error {
+	now := c.TimeFunc()
+
+	c.resolvedMu.Lock()
+	defer c.resolvedMu.Unlock()
+	for feed, resolved := range c.resolved {
+		addResolved(acc, feed, resolved, now)
+	}
+	return nil
+}
+
+// Start starts the webhook listener service.
+func (c *CockroachdbChangefeed) Start(acc telegraf.Accumulator) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.MaxBodySize == 0 {
+		c.MaxBodySize = DEFAULT_MAX_BODY_SIZE
+	}
+	if c.ReadTimeout.Duration < time.Second {
+		c.ReadTimeout.Duration = time.Second * 10
+	}
+	if c.WriteTimeout.Duration < time.Second {
+		c.WriteTimeout.Duration = time.Second * 10
+	}
+	if c.Path == "" {
+		c.Path = "/changefeed"
+	}
+	if c.Measurement == "" {
+		c.Measurement = "cockroachdb_changefeed"
+	}
+
+	c.acc = acc
+	c.resolved = make(map[string]time.Time)
+
+	tlsConf, err := c.ServerConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+
+	server := &http.Server{
+		Addr:         c.ServiceAddress,
+		Handler:      c,
+		ReadTimeout:  c.ReadTimeout.Duration,
+		WriteTimeout: c.WriteTimeout.Duration,
+		TLSConfig:    tlsConf,
+	}
+
+	var listener net.Listener
+	if tlsConf != nil {
+		listener, err = tls.Listen("tcp", c.ServiceAddress, tlsConf)
+	} else {
+		listener, err = net.Listen("tcp", c.ServiceAddress)
+	}
+	if err != nil {
+		return err
+	}
+	c.listener = listener
+	c.Port = listener.Addr().(*net.TCPAddr).Port
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		server.Serve(c.listener)
+	}()
+
+	log.Printf("I! Started CockroachDB changefeed listener service on %s\n", c.ServiceAddress)
+
+	return nil
+}
+
+// Stop cleans up all resources
+func (c *CockroachdbChangefeed) Stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.listener.Close()
+	c.wg.Wait()
+
+	log.Println("I! Stopped CockroachDB changefeed listener service on ", c.ServiceAddress)
+}
+
+func (c *CockroachdbChangefeed) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	feed, ok := c.feed(req.URL.Path)
+	if !ok {
+		c.AuthenticateIfSet(http.NotFound, res, req)
+		return
+	}
+	c.AuthenticateIfSet(func(res http.ResponseWriter, req *http.Request) {
+		c.serveWebhook(feed, res, req)
+	}, res, req)
+}
+
+// feed returns the changefeed name of a request path, the sub-path of Path.
+func (c *CockroachdbChangefeed) feed(path string) (string, bool) {
+	prefix := strings.TrimRight(c.Path, "/")
+	if path == prefix || path == prefix+"/" {
+		return "", true
+	}
+	if !strings.HasPrefix(path, prefix+"/") {
+		return "", false
+	}
+	return strings.Trim(strings.TrimPrefix(path, prefix), "/"), true
+}
+
+func (c *CockroachdbChangefeed) serveWebhook(feed string, res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(res, "Method not allowed.", http.StatusMethodNotAllowed)
+		return
+	}
+	if req.ContentLength > c.MaxBodySize {
+		http.Error(res, "http: request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	now := c.TimeFunc()
+
+	body := http.MaxBytesReader(res, req.Body, c.MaxBodySize)
+	dec := json.NewDecoder(body)
+	dec.UseNumber()
+
+	var batch webhookBody
+	if err := dec.Decode(&batch); err != nil {
+		log.Println("E! " + err.Error())
+		http.Error(res, "http: bad request", http.StatusBadRequest)
+		return
+	}
+
+	if err := c.addBatch(feed, &batch, now); err != nil {
+		log.Println("E! " + err.Error())
+		http.Error(res, "http: bad request", http.StatusBadRequest)
+		return
+	}
+	res.WriteHeader(http.StatusOK)
+}
+
+// point is a metric parsed from a row.
+type point struct {
+	fields map[string]interface{}
+	tags   map[string]string
+	ts     time.Time
+}
+
+// addBatch adds a metric for every row of the batch, or a resolved metric
+// with the changefeed lag for a resolved timestamp. Nothing is added from a
+// batch with an invalid row, as the sink retries the whole batch.
+func (c *CockroachdbChangefeed) addBatch(feed string, batch *webhookBody, now time.Time) error {
+	if batch.Resolved != "" {
+		resolved, err := parseHLC(batch.Resolved)
+		if err != nil {
+			return err
+		}
+		c.resolvedMu.Lock()
+		if resolved.After(c.resolved[feed]) {
+			c.resolved[feed] = resolved
+		}
+		c.resolvedMu.Unlock()
+		addResolved(c.acc, feed, resolved, now)
+		return nil
+	}
+
+	var points []*point
+	for _, row := range batch.Payload {
+		// Deleted rows have no columns
+		if len(row.After) == 0 || string(row.After) == "null" {
+			continue
+		}
+
+		dec := json.NewDecoder(bytes.NewReader(row.After))
+		dec.UseNumber()
+		var columns map[string]interface{}
+		if err := dec.Decode(&columns); err != nil {
+			return err
+		}
+
+		tags := map[string]string{}
+		if feed != "" {
+			tags["feed"] = feed
+		}
+		if row.Topic != "" {
+			tags["topic"] = row.Topic
+		}
+
+		ts := now
+		if row.Updated != "" {
+			updated, err := parseHLC(row.Updated)
+			if err != nil {
+				return err
+			}
+			ts = updated
+		}
+
+		p, err := c.parseRow(columns, tags, ts)
+		if err != nil {
+			return err
+		}
+		if len(p.fields) > 0 {
+			points = append(points, p)
+		}
+	}
+
+	for _, p := range points {
+		c.acc.AddFields(c.Measurement, p.fields, p.tags, p.ts)
+	}
+	return nil
+}
+
+// addResolved adds the resolved timestamp of a changefeed and its lag at now.
+func addResolved(acc telegraf.Accumulator, feed string, resolved time.Time, now time.Time) {
+	tags := map[string]string{}
+	if feed != "" {
+		tags["feed"] = feed
+	}
+	acc.AddFields("cockroachdb_changefeed_resolved", map[string]interface{}{
+		"resolved":    resolved.UnixNano(),
+		"lag_seconds": now.Sub(resolved).Seconds(),
+	}, tags, now)
+}
+
+// parseRow maps the columns of a row to the tags, fields and timestamp of a
+// metric.
+func (c *CockroachdbChangefeed) parseRow(columns map[string]interface{}, tags map[string]string, ts time.Time) (*point, error) {
+	if c.TimestampColumn != "" {
+		if v, ok := columns[c.TimestampColumn]; ok && v != nil {
+			t, err := parseTimestamp(v, c.TimestampFormat)
+			if err != nil {
+				return nil, fmt.Errorf("column %s: %s", c.TimestampColumn, err)
+			}
+			ts = t
+		}
+	}
+
+	isTag := make(map[string]bool, len(c.TagColumns))
+	for _, name := range c.TagColumns {
+		isTag[name] = true
+		if v, ok := columns[name]; ok && v != nil {
+			tags[name] = fmt.Sprint(v)
+		}
+	}
+
+	names := c.FieldColumns
+	if len(names) == 0 {
+		names = make([]string, 0, len(columns))
+		for name := range columns {
+			if isTag[name] || name == c.TimestampColumn {
+				continue
+			}
+			names = append(names, name)
+		}
+	}
+
+	fields := make(map[string]interface{})
+	for _, name := range names {
+		v, ok, err := c.fieldValue(name, columns[name])
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %s", name, err)
+		}
+		if ok {
+			fields[name] = v
+		}
+	}
+
+	return &point{fields: fields, tags: tags, ts: ts}, nil
+}
+
+// fieldValue converts a JSON column value to a field value. Numbers become
+// int64 in the integer_columns and float64 otherwise, whatever their value,
+// so that a field keeps its type from row to row; NULL, arrays and objects
+// are skipped.
+func (c *CockroachdbChangefeed) fieldValue(name string, v interface{}) (interface{}, bool, error) {
+	switch v := v.(type) {
+	case json.Number:
+		for _, column := range c.IntegerColumns {
+			if column == name {
+				i, err := v.Int64()
+				if err != nil {
+					return nil, false, fmt.Errorf("%s is not an integer", v)
+				}
+				return i, true, nil
+			}
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return nil, false, err
+		}
+		return f, true, nil
+	case string:
+		return v, true, nil
+	case bool:
+		return v, true, nil
+	}
+	return nil, false, nil
+}
+
+// parseHLC parses the decimal representation of an HLC timestamp,
+// <wall time in nanoseconds>.<logical clock>, as used by the resolved and
+// updated timestamps of a changefeed.
+func parseHLC(s string) (time.Time, error) {
+	wall := s
+	if i := strings.Index(s, "."); i >= 0 {
+		wall = s[:i]
+	}
+	ns, err := strconv.ParseInt(wall, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid HLC timestamp %q", s)
+	}
+	return time.Unix(0, ns), nil
+}
+
+// parseTimestamp parses the value of the timestamp column according to
+// format.
+func parseTimestamp(v interface{}, format string) (time.Time, error) {
+	switch v := v.(type) {
+	case json.Number:
+		var unit time.Duration
+		switch format {
+		case "", "unix":
+			unit = time.Second
+		case "unix_ms":
+			unit = time.Millisecond
+		case "unix_us":
+			unit = time.Microsecond
+		case "unix_ns":
+			unit = time.Nanosecond
+		default:
+			return time.Time{}, fmt.Errorf("number %s does not match timestamp_format %q", v, format)
+		}
+		// Integers are kept exact, as float64 cannot hold nanoseconds
+		if i, err := v.Int64(); err == nil {
+			return time.Unix(0, i*int64(unit)), nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.Unix(0, int64(f*float64(unit))), nil
+	case string:
+		if format != "" {
+			return time.Parse(format, v)
+		}
+		// TIMESTAMPTZ columns carry an offset, TIMESTAMP columns do not
+		if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			return t, nil
+		}
+		return time.Parse("2006-01-02T15:04:05.999999999", v)
+	}
+	return time.Time{}, fmt.Errorf("unsupported timestamp %v", v)
+}
+
+func (c *CockroachdbChangefeed) AuthenticateIfSet(handler http.HandlerFunc, res http.ResponseWriter, req *http.Request) {
+	if c.BasicUsername != "" && c.BasicPassword != "" {
+		reqUsername, reqPassword, ok := req.BasicAuth()
+		if !ok ||
+			subtle.ConstantTimeCompare([]byte(reqUsername), []byte(c.BasicUsername)) != 1 ||
+			subtle.ConstantTimeCompare([]byte(reqPassword), []byte(c.BasicPassword)) != 1 {
+
+			http.Error(res, "Unauthorized.", http.StatusUnauthorized)
+			return
+		}
+		handler(res, req)
+	} else {
+		handler(res, req)
+	}
+}
+
+func init() {
+	inputs.Add("cockroachdb_changefeed", func() telegraf.Input {
+		return &CockroachdbChangefeed{
+			ServiceAddress: ":8188",
+			Path:           "/changefeed",
+			Measurement:    "cockroachdb_changefeed",
+			TimeFunc:       time.Now,
+		}
+	})
+}
@@ -0,0 +1,346 @@
+package cockroachdb_changefeed
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	rowsMsg = `{"payload":[` +
+		`{"after":{"id":1,"region":"us-east1","status":"shipped","total":12.5,"items":3,"paid":true,"notes":null,"meta":{"a":1}},"key":[1],"topic":"orders","updated":"1539216000000000000.0000000000"},` +
+		`{"after":null,"key":[2],"topic":"orders","updated":"1539216001000000000.0000000000"},` +
+		`{"after":{"id":3,"region":"eu-west1","status":"pending","total":7,"items":1,"paid":false},"key":[3],"topic":"orders","updated":"1539216002000000000.0000000001"}` +
+		`],"length":3}`
+
+	timestampRowsMsg = `{"payload":[` +
+		`{"after":{"id":1,"created_at":"2018-10-11T00:00:00.5","total":12.5,"items":3},"key":[1],"topic":"orders"}` +
+		`],"length":1}`
+
+	resolvedMsg = `{"resolved":"1539216000000000000.0000000000"}`
+
+	invalidRowMsg = `{"payload":[` +
+		`{"after":{"id":1,"total":12.5},"key":[1],"topic":"orders","updated":"1539216000000000000.0000000000"},` +
+		`{"after":{"id":2,"total":7},"key":[2],"topic":"orders","updated":"yesterday"}` +
+		`],"length":2}`
+
+	badMsg = `{"payload":[`
+
+	basicUsername = "test-username-please-ignore"
+	basicPassword = "super-secure-password!"
+)
+
+var (
+	pki = testutil.NewPKI("../../../testutil/pki")
+)
+
+func newTestListener() *CockroachdbChangefeed {
+	listener := &CockroachdbChangefeed{
+		ServiceAddress: "localhost:0",
+		TimeFunc: func() time.Time {
+			return time.Unix(0, 1539216012500000000)
+		},
+	}
+	return listener
+}
+
+func createURL(listener *CockroachdbChangefeed, scheme string, path string) string {
+	u := url.URL{
+		Scheme: scheme,
+		Host:   "localhost:" + strconv.Itoa(listener.Port),
+		Path:   path,
+	}
+	return u.String()
+}
+
+func post(t *testing.T, listener *CockroachdbChangefeed, path string, body string) int {
+	resp, err := http.Post(createURL(listener, "http", path), "application/json", bytes.NewBufferString(body))
+	require.NoError(t, err)
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestWriteRows(t *testing.T) {
+	listener := newTestListener()
+	listener.TagColumns = []string{"region"}
+	listener.IntegerColumns = []string{"id", "items"}
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, listener.Start(acc))
+	defer listener.Stop()
+
+	require.EqualValues(t, 200, post(t, listener, "/changefeed/orders", rowsMsg))
+
+	// The deleted row is skipped
+	require.Len(t, acc.Metrics, 2)
+
+	acc.AssertContainsTaggedFields(t, "cockroachdb_changefeed",
+		map[string]interface{}{
+			"id":     int64(1),
+			"status": "shipped",
+			"total":  12.5,
+			"items":  int64(3),
+			"paid":   true,
+		},
+		map[string]string{"feed": "orders", "topic": "orders", "region": "us-east1"},
+	)
+	acc.AssertContainsTaggedFields(t, "cockroachdb_changefeed",
+		map[string]interface{}{
+			"id":     int64(3),
+			"status": "pending",
+			"total":  float64(7),
+			"items":  int64(1),
+			"paid":   false,
+		},
+		map[string]string{"feed": "orders", "topic": "orders", "region": "eu-west1"},
+	)
+
+	// Rows are timestamped with their updated timestamp
+	assert.Equal(t, time.Unix(0, 1539216000000000000), acc.Metrics[0].Time)
+	assert.Equal(t, time.Unix(0, 1539216002000000000), acc.Metrics[1].Time)
+}
+
+func TestWriteRowsFloatColumns(t *testing.T) {
+	listener := newTestListener()
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, listener.Start(acc))
+	defer listener.Stop()
+
+	require.EqualValues(t, 200, post(t, listener, "/changefeed/orders", rowsMsg))
+
+	// Numbers outside of integer_columns are floats, whole or not
+	require.Len(t, acc.Metrics, 2)
+	for _, m := range acc.Metrics {
+		for _, name := range []string{"id", "total", "items"} {
+			assert.IsType(t, float64(0), m.Fields[name], name)
+		}
+	}
+}
+
+func TestWriteRowsIntegerColumnInvalid(t *testing.T) {
+	listener := newTestListener()
+	listener.IntegerColumns = []string{"total"}
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, listener.Start(acc))
+	defer listener.Stop()
+
+	// total is 12.5 in the first row
+	require.EqualValues(t, 400, post(t, listener, "/changefeed/orders", rowsMsg))
+	assert.Empty(t, acc.Metrics)
+}
+
+func TestWriteRowsTimestampColumn(t *testing.T) {
+	listener := newTestListener()
+	listener.FieldColumns = []string{"total"}
+	listener.TimestampColumn = "created_at"
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, listener.Start(acc))
+	defer listener.Stop()
+
+	require.EqualValues(t, 200, post(t, listener, "/changefeed", timestampRowsMsg))
+
+	require.Len(t, acc.Metrics, 1)
+	acc.AssertContainsTaggedFields(t, "cockroachdb_changefeed",
+		map[string]interface{}{"total": 12.5},
+		map[string]string{"topic": "orders"},
+	)
+	assert.Equal(t, time.Date(2018, 10, 11, 0, 0, 0, 500000000, time.UTC), acc.Metrics[0].Time)
+}
+
+func TestWriteRowsNoTimestamp(t *testing.T) {
+	listener := newTestListener()
+	listener.Measurement = "orders"
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, listener.Start(acc))
+	defer listener.Stop()
+
+	// Without updated nor timestamp_column rows are timestamped when received
+	require.EqualValues(t, 200, post(t, listener, "/changefeed", timestampRowsMsg))
+
+	require.Len(t, acc.Metrics, 1)
+	assert.Equal(t, "orders", acc.Metrics[0].Measurement)
+	assert.Equal(t, "2018-10-11T00:00:00.5", acc.Metrics[0].Fields["created_at"])
+	assert.Equal(t, listener.TimeFunc(), acc.Metrics[0].Time)
+}
+
+func TestWriteResolved(t *testing.T) {
+	listener := newTestListener()
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, listener.Start(acc))
+	defer listener.Stop()
+
+	require.EqualValues(t, 200, post(t, listener, "/changefeed/orders", resolvedMsg))
+
+	acc.AssertContainsTaggedFields(t, "cockroachdb_changefeed_resolved",
+		map[string]interface{}{
+			"resolved":    int64(1539216000000000000),
+			"lag_seconds": 12.5,
+		},
+		map[string]string{"feed": "orders"},
+	)
+}
+
+func TestGatherResolvedLag(t *testing.T) {
+	listener := newTestListener()
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, listener.Start(acc))
+	defer listener.Stop()
+
+	// Nothing is reported before the first resolved timestamp
+	require.NoError(t, listener.Gather(acc))
+	assert.Empty(t, acc.Metrics)
+
+	require.EqualValues(t, 200, post(t, listener, "/changefeed/orders", resolvedMsg))
+	acc.ClearMetrics()
+
+	// The lag of a changefeed that stopped sending resolved timestamps grows
+	listener.TimeFunc = func() time.Time {
+		return time.Unix(0, 1539216060000000000)
+	}
+	require.NoError(t, listener.Gather(acc))
+	acc.AssertContainsTaggedFields(t, "cockroachdb_changefeed_resolved",
+		map[string]interface{}{
+			"resolved":    int64(1539216000000000000),
+			"lag_seconds": float64(60),
+		},
+		map[string]string{"feed": "orders"},
+	)
+}
+
+func TestWriteHTTPS(t *testing.T) {
+	listener := newTestListener()
+	listener.ServerConfig = *pki.TLSServerConfig()
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, listener.Start(acc))
+	defer listener.Stop()
+
+	tlsConfig, err := pki.TLSClientConfig().TLSConfig()
+	require.NoError(t, err)
+	client := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: tlsConfig,
+		},
+	}
+
+	resp, err := client.Post(createURL(listener, "https", "/changefeed"), "application/json", bytes.NewBufferString(resolvedMsg))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.EqualValues(t, 200, resp.StatusCode)
+	assert.True(t, acc.HasMeasurement("cockroachdb_changefeed_resolved"))
+}
+
+func TestWriteBasicAuth(t *testing.T) {
+	listener := newTestListener()
+	listener.BasicUsername = basicUsername
+	listener.BasicPassword = basicPassword
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, listener.Start(acc))
+	defer listener.Stop()
+
+	require.EqualValues(t, http.StatusUnauthorized, post(t, listener, "/changefeed", resolvedMsg))
+
+	req, err := http.NewRequest("POST", createURL(listener, "http", "/changefeed"), bytes.NewBufferString(resolvedMsg))
+	require.NoError(t, err)
+	req.SetBasicAuth(basicUsername, basicPassword)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.EqualValues(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestWriteInvalid(t *testing.T) {
+	listener := newTestListener()
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, listener.Start(acc))
+	defer listener.Stop()
+
+	require.EqualValues(t, 400, post(t, listener, "/changefeed", badMsg))
+
+	// A batch with an invalid row is rejected as a whole
+	require.EqualValues(t, 400, post(t, listener, "/changefeed", invalidRowMsg))
+	assert.Empty(t, acc.Metrics)
+}
+
+func TestWriteTooLarge(t *testing.T) {
+	listener := newTestListener()
+	listener.MaxBodySize = 16
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, listener.Start(acc))
+	defer listener.Stop()
+
+	require.EqualValues(t, 413, post(t, listener, "/changefeed", rowsMsg))
+	assert.Empty(t, acc.Metrics)
+}
+
+func TestReceive404ForInvalidEndpoint(t *testing.T) {
+	listener := newTestListener()
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, listener.Start(acc))
+	defer listener.Stop()
+
+	require.EqualValues(t, 404, post(t, listener, "/write", resolvedMsg))
+	require.EqualValues(t, 404, post(t, listener, "/changefeeds", resolvedMsg))
+}
+
+func TestReceive405ForGet(t *testing.T) {
+	listener := newTestListener()
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, listener.Start(acc))
+	defer listener.Stop()
+
+	resp, err := http.Get(createURL(listener, "http", "/changefeed"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.EqualValues(t, 405, resp.StatusCode)
+}
+
+func TestParseTimestamp(t *testing.T) {
+	tests := []struct {
+		value    interface{}
+		format   string
+		expected time.Time
+	}{
+		{json.Number("1539216000"), "", time.Unix(1539216000, 0)},
+		{json.Number("1539216000.5"), "unix", time.Unix(1539216000, 500000000)},
+		{json.Number("1539216000500"), "unix_ms", time.Unix(1539216000, 500000000)},
+		{json.Number("1539216000500000"), "unix_us", time.Unix(1539216000, 500000000)},
+		{json.Number("1539216000500000000"), "unix_ns", time.Unix(1539216000, 500000000)},
+		{json.Number("1539216000123456789"), "unix_ns", time.Unix(1539216000, 123456789)},
+		{json.Number("1539216000123456"), "unix_us", time.Unix(1539216000, 123456000)},
+		{"2018-10-11T00:00:00.5Z", "", time.Date(2018, 10, 11, 0, 0, 0, 500000000, time.UTC)},
+		{"2018-10-11T02:00:00+02:00", "", time.Date(2018, 10, 11, 0, 0, 0, 0, time.UTC)},
+		{"2018-10-11T00:00:00", "", time.Date(2018, 10, 11, 0, 0, 0, 0, time.UTC)},
+		{"11/10/2018 00:00", "02/01/2006 15:04", time.Date(2018, 10, 11, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		ts, err := parseTimestamp(tt.value, tt.format)
+		require.NoError(t, err, tt.value)
+		assert.True(t, tt.expected.Equal(ts), "%v: expected %s, got %s", tt.value, tt.expected, ts)
+	}
+
+	_, err := parseTimestamp(json.Number("1539216000"), "2006-01-02")
+	assert.Error(t, err)
+	_, err = parseTimestamp(true, "")
+	assert.Error(t, err)
+}